@@ -1,26 +1,100 @@
 package exporter
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
 )
 
-var urlFormat = "%s://%s:%s@%s:%s/CMD_API_ADMIN_STATS?json=yes"
-var mockIOReadAll = io.ReadAll
+var labelNameRegexp = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+// coreLabels lists the labels of the metrics exported for every target,
+// the target labels must not collide with them.
+var coreLabels = []string{"reason", "hostname", "device", "mountpoint",
+	"resource", "kind"}
+
+// defaultFilesystemExclude matches the devices of pseudo filesystems.
+var defaultFilesystemExclude = "^(tmpfs|devtmpfs)$"
+
+// defaultInfoFields lists the string fields promoted to labels of
+// the directadmin_server_info metric by default.
+var defaultInfoFields = []string{"device"}
 
 // APIConfiguration represents the configuration data for the API.
 type APIConfiguration struct {
-	Hostname string `validate:"required,hostname|ip"`
-	Protocol string `validate:"required,oneof=http https"`
-	Port     string `validate:"required,number"`
-	Username string `validate:"required"`
-	Token    string `validate:"required"`
+	Name     string `yaml:"name"`
+	Hostname string `yaml:"hostname" validate:"required,hostname|ip"`
+	Protocol string `yaml:"protocol" validate:"required,oneof=http https"`
+	Port     string `yaml:"port" validate:"required,number"`
+	Username string `yaml:"username" validate:"required"`
+	Token    string `yaml:"token" validate:"required"`
+
+	// Optional settings
+	Timeout           time.Duration     `yaml:"timeout" validate:"gte=0"`
+	ConnectTimeout    time.Duration     `yaml:"connect_timeout" validate:"gte=0"`
+	ReadTimeout       time.Duration     `yaml:"read_timeout" validate:"gte=0"`
+	Labels            map[string]string `yaml:"labels"`
+	FilesystemExclude string            `yaml:"filesystem_exclude"`
+	IndexArrays       bool              `yaml:"index_arrays"`
+
+	// FlattenUnknownFields exports the fields unknown to the AdminStats
+	// schema with the generic flattener.
+	FlattenUnknownFields bool `yaml:"flatten_unknown_fields"`
+
+	// TallyAge exports the number of seconds since the last tally,
+	// computed at scrape time.
+	TallyAge bool `yaml:"tally_age"`
+
+	// InfoFields lists the string fields of the API response promoted to
+	// labels of the directadmin_server_info metric.
+	InfoFields []string `yaml:"info_fields"`
+
+	// API is the API of DirectAdmin the target is requested with,
+	// APILegacy or APIREST.
+	API string `yaml:"api"`
+
+	// SessionAuth logs in to the REST API and sends the session cookie
+	// instead of the credentials with every request.
+	SessionAuth bool `yaml:"session_auth"`
+
+	// ResponseFormat is the format of the API responses, FormatJSON,
+	// FormatURLEncoded or FormatAuto choosing it from the Content-Type.
+	ResponseFormat string `yaml:"response_format"`
+
+	// TLS holds the TLS settings of the connections to the API.
+	TLS TLSConfiguration `yaml:"tls"`
+
+	// Retry holds the retry settings of the requests to the API.
+	Retry RetryConfiguration `yaml:"retry"`
+
+	// CircuitBreaker holds the settings of the circuit breaker stopping
+	// the requests to a failing target.
+	CircuitBreaker CircuitBreakerConfiguration `yaml:"circuit_breaker"`
+
+	// Users holds the settings of the opt-in per-user collector.
+	Users UserCollectorConfiguration `yaml:"users"`
+
+	// Resellers holds the settings of the opt-in reseller collector.
+	Resellers ResellerCollectorConfiguration `yaml:"resellers"`
+
+	// Domains holds the settings of the opt-in domain collector.
+	Domains DomainCollectorConfiguration `yaml:"domains"`
+
+	// Services holds the settings of the opt-in service collector.
+	Services ServiceCollectorConfiguration `yaml:"services"`
+
+	// License holds the settings of the opt-in license collector.
+	License LicenseCollectorConfiguration `yaml:"license"`
 }
 
 // NewAPIConfiguration returns a new APIConfiguration struct filled with data
@@ -31,6 +105,28 @@ func NewAPIConfiguration(filenames ...string) APIConfiguration {
 	if err != nil {
 		log.Println(err)
 	}
+	indexArrays, _ := strconv.ParseBool(os.Getenv("DIRECTADMIN_INDEX_ARRAYS"))
+	flattenUnknownFields, _ := strconv.ParseBool(
+		os.Getenv("DIRECTADMIN_FLATTEN_UNKNOWN_FIELDS"))
+	tallyAge, _ := strconv.ParseBool(os.Getenv("DIRECTADMIN_TALLY_AGE"))
+	sessionAuth, _ := strconv.ParseBool(
+		os.Getenv("DIRECTADMIN_SESSION_AUTH"))
+	insecureSkipVerify, _ := strconv.ParseBool(
+		os.Getenv("DIRECTADMIN_TLS_INSECURE_SKIP_VERIFY"))
+	usersEnabled, _ := strconv.ParseBool(
+		os.Getenv("DIRECTADMIN_USERS_ENABLED"))
+	resellersEnabled, _ := strconv.ParseBool(
+		os.Getenv("DIRECTADMIN_RESELLERS_ENABLED"))
+	domainsEnabled, _ := strconv.ParseBool(
+		os.Getenv("DIRECTADMIN_DOMAINS_ENABLED"))
+	servicesEnabled, _ := strconv.ParseBool(
+		os.Getenv("DIRECTADMIN_SERVICES_ENABLED"))
+	licenseEnabled, _ := strconv.ParseBool(
+		os.Getenv("DIRECTADMIN_LICENSE_ENABLED"))
+	var infoFields []string
+	if fields := os.Getenv("DIRECTADMIN_INFO_FIELDS"); fields != "" {
+		infoFields = strings.Split(fields, ",")
+	}
 
 	return APIConfiguration{
 		Hostname: os.Getenv("DIRECTADMIN_HOSTNAME"),
@@ -38,33 +134,240 @@ func NewAPIConfiguration(filenames ...string) APIConfiguration {
 		Port:     os.Getenv("DIRECTADMIN_PORT"),
 		Username: os.Getenv("DIRECTADMIN_USERNAME"),
 		Token:    os.Getenv("DIRECTADMIN_TOKEN"),
+
+		Timeout:           durationEnv("DIRECTADMIN_TIMEOUT"),
+		ConnectTimeout:    durationEnv("DIRECTADMIN_CONNECT_TIMEOUT"),
+		ReadTimeout:       durationEnv("DIRECTADMIN_READ_TIMEOUT"),
+		FilesystemExclude: os.Getenv("DIRECTADMIN_FILESYSTEM_EXCLUDE"),
+		IndexArrays:       indexArrays,
+
+		FlattenUnknownFields: flattenUnknownFields,
+		TallyAge:             tallyAge,
+		InfoFields:           infoFields,
+		API:                  os.Getenv("DIRECTADMIN_API"),
+		SessionAuth:          sessionAuth,
+		ResponseFormat:       os.Getenv("DIRECTADMIN_RESPONSE_FORMAT"),
+
+		TLS: TLSConfiguration{
+			CAFile:             os.Getenv("DIRECTADMIN_TLS_CA_FILE"),
+			CertFile:           os.Getenv("DIRECTADMIN_TLS_CERT_FILE"),
+			KeyFile:            os.Getenv("DIRECTADMIN_TLS_KEY_FILE"),
+			ServerName:         os.Getenv("DIRECTADMIN_TLS_SERVER_NAME"),
+			MinVersion:         os.Getenv("DIRECTADMIN_TLS_MIN_VERSION"),
+			InsecureSkipVerify: insecureSkipVerify,
+		},
+		Retry: RetryConfiguration{
+			Retries:    intEnv("DIRECTADMIN_RETRIES"),
+			Backoff:    durationEnv("DIRECTADMIN_RETRY_BACKOFF"),
+			MaxBackoff: durationEnv("DIRECTADMIN_RETRY_MAX_BACKOFF"),
+		},
+		CircuitBreaker: CircuitBreakerConfiguration{
+			Threshold: intEnv("DIRECTADMIN_CIRCUIT_THRESHOLD"),
+			Cooldown:  durationEnv("DIRECTADMIN_CIRCUIT_COOLDOWN"),
+		},
+		Users: UserCollectorConfiguration{
+			Enabled:  usersEnabled,
+			Include:  os.Getenv("DIRECTADMIN_USERS_INCLUDE"),
+			Exclude:  os.Getenv("DIRECTADMIN_USERS_EXCLUDE"),
+			MaxUsers: intEnv("DIRECTADMIN_USERS_MAX"),
+		},
+		Resellers: ResellerCollectorConfiguration{
+			Enabled:  resellersEnabled,
+			Interval: durationEnv("DIRECTADMIN_RESELLERS_INTERVAL"),
+			Timeout:  durationEnv("DIRECTADMIN_RESELLERS_TIMEOUT"),
+		},
+		Domains: DomainCollectorConfiguration{
+			Enabled:  domainsEnabled,
+			Interval: durationEnv("DIRECTADMIN_DOMAINS_INTERVAL"),
+			Timeout:  durationEnv("DIRECTADMIN_DOMAINS_TIMEOUT"),
+		},
+		Services: ServiceCollectorConfiguration{
+			Enabled: servicesEnabled,
+		},
+		License: LicenseCollectorConfiguration{
+			Enabled: licenseEnabled,
+		},
 	}
 }
 
+// durationEnv returns the duration read from the environment variable. It
+// returns zero, meaning no timeout, when the variable is unset or invalid.
+func durationEnv(name string) time.Duration {
+	duration, _ := time.ParseDuration(os.Getenv(name))
+	return duration
+}
+
+// intEnv returns the integer read from the environment variable. It returns
+// zero when the variable is unset or invalid.
+func intEnv(name string) int {
+	value, _ := strconv.Atoi(os.Getenv(name))
+	return value
+}
+
 // ValidateAPIConfiguration validates the APIConfiguration data.
 func ValidateAPIConfiguration(config APIConfiguration) error {
 	validate := validator.New()
-	return validate.Struct(config)
+	validate.RegisterTagNameFunc(yamlFieldName)
+	if err := validate.Struct(config); err != nil {
+		return err
+	}
+
+	// Validate label names
+	if err := validateLabels(config); err != nil {
+		return err
+	}
+
+	// Validate filesystem filter
+	if _, err := regexp.Compile(config.FilesystemExclude); err != nil {
+		return err
+	}
+
+	// Validate API settings
+	if err := validateBackend(config); err != nil {
+		return err
+	}
+
+	// Validate TLS settings
+	if _, err := newTLSConfig(config.TLS); err != nil {
+		return err
+	}
+
+	// Validate opt-in collectors
+	if err := validateCollectors(config); err != nil {
+		return err
+	}
+
+	return validateInfoFields(config)
 }
 
-// APIRequest performs a request to the DirectAdmin API.
-func APIRequest(config APIConfiguration) ([]byte, error) {
-	// Perform a request to the DirectAdmin API
-	resp, err := http.Get(fmt.Sprintf(urlFormat, config.Protocol,
-		config.Username, config.Token, config.Hostname, config.Port))
-	if err != nil {
-		log.Println(err)
-		return []byte{}, err
+// yamlFieldName returns the YAML key of the struct field, so the validation
+// errors name the settings as they are written in the configuration file.
+func yamlFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	return name
+}
+
+// validateBackend checks the API and the response format of the target.
+// Session auth is supported by the REST API only, which always answers with
+// JSON.
+func validateBackend(config APIConfiguration) error {
+	if _, exists := backends[config.API]; !exists {
+		return fmt.Errorf("invalid API %q", config.API)
+	}
+	if config.SessionAuth && config.API != APIREST {
+		return errors.New("session auth requires the REST API")
+	}
+	if config.API == APIREST && config.ResponseFormat == FormatURLEncoded {
+		return errors.New("the REST API doesn't support the URL-encoded " +
+			"format")
+	}
+	if !slices.Contains(responseFormats, config.ResponseFormat) {
+		return fmt.Errorf("invalid response format %q",
+			config.ResponseFormat)
+	}
+	return nil
+}
+
+// isLabelName reports whether the name is a valid label name. Names
+// prefixed with __ are reserved by Prometheus.
+func isLabelName(name string) bool {
+	return labelNameRegexp.MatchString(name) &&
+		!strings.HasPrefix(name, "__")
+}
+
+// validateLabels checks the names of the target labels. They must not
+// collide with the labels of the core metrics and of the enabled opt-in
+// collectors, which would fail every scrape of the target.
+func validateLabels(config APIConfiguration) error {
+	reserved := append(slices.Clone(coreLabels), collectorLabels(config)...)
+	for name := range config.Labels {
+		if !isLabelName(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+		if slices.Contains(reserved, name) {
+			return fmt.Errorf("label %q is reserved by a metric of "+
+				"the target", name)
+		}
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	// Read the response body
-	body, err := mockIOReadAll(resp.Body)
-	if err != nil {
-		log.Println(err)
-		return []byte{}, err
+// validateCollectors checks the settings of the opt-in collectors.
+func validateCollectors(config APIConfiguration) error {
+	users := config.Users
+	for _, expression := range []string{users.Include, users.Exclude} {
+		if _, err := regexp.Compile(expression); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectorLabels returns the labels of the metrics of the enabled opt-in
+// collectors.
+func collectorLabels(config APIConfiguration) []string {
+	labels := []string{}
+	if config.Users.Enabled {
+		labels = append(labels, userLabels...)
+	}
+	if config.Resellers.Enabled {
+		labels = append(labels, resellerLabels...)
+	}
+	if config.Domains.Enabled {
+		labels = append(labels, domainLabels...)
+	}
+	if config.Services.Enabled {
+		labels = append(labels, serviceLabels...)
+	}
+	if config.License.Enabled {
+		labels = append(labels, licenseLabels...)
+	}
+	return labels
+}
+
+// validateInfoFields checks that the info fields are valid label names
+// which don't collide with the hostname and the target labels.
+func validateInfoFields(config APIConfiguration) error {
+	fields := infoFields(config)
+	for i, field := range fields {
+		_, exists := config.Labels[field]
+		if !isLabelName(field) || field == "hostname" || exists {
+			return fmt.Errorf("invalid info field %q", field)
+		}
+		if slices.Contains(fields[:i], field) {
+			return fmt.Errorf("duplicate info field %q", field)
+		}
+	}
+	return nil
+}
+
+// infoFields returns the string fields promoted to labels of
+// the directadmin_server_info metric.
+func infoFields(config APIConfiguration) []string {
+	if config.InfoFields == nil {
+		return defaultInfoFields
 	}
+	return config.InfoFields
+}
 
-	// Return the response body
-	return body, nil
+// filesystemExclude returns the expression matching the devices of
+// the filesystems which are not exported.
+func filesystemExclude(config APIConfiguration) *regexp.Regexp {
+	if config.FilesystemExclude == "" {
+		return regexp.MustCompile(defaultFilesystemExclude)
+	}
+	return regexp.MustCompile(config.FilesystemExclude)
+}
+
+// logError logs the error with the token of the target redacted.
+func logError(config APIConfiguration, err error) {
+	log.Println(redact(err.Error(), config.Token))
+}
+
+// redact replaces the secret in the message with asterisks.
+func redact(message string, secret string) string {
+	if secret == "" {
+		return message
+	}
+	return strings.ReplaceAll(message, secret, "***")
 }
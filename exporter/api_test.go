@@ -4,11 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/joho/godotenv"
@@ -56,6 +57,10 @@ func TestNewAPIConfiguration(t *testing.T) {
 		Port:     "2222",
 		Username: "admin",
 		Token:    "SECRET_TOKEN",
+
+		ConnectTimeout: 2 * time.Second,
+		InfoFields:     []string{"device", "version"},
+		Retry:          RetryConfiguration{Retries: 2},
 	}
 
 	// Test
@@ -196,6 +201,299 @@ func TestValidateAPIConfiguration(t *testing.T) {
 			},
 			expected: errors.New("Missing username"),
 		},
+		{
+			name: "Invalid filesystem filter",
+			config: APIConfiguration{
+				Hostname:          "s1.hostname.com",
+				Protocol:          "http",
+				Port:              "2222",
+				Username:          "admin",
+				Token:             "SECRET",
+				FilesystemExclude: "(",
+			},
+			expected: errors.New("Invalid filesystem filter"),
+		},
+		{
+			name: "Valid TLS settings",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "https",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				TLS: TLSConfiguration{
+					CAFile:     "../testing/tls/client.pem",
+					CertFile:   "../testing/tls/client.pem",
+					KeyFile:    "../testing/tls/client-key.pem",
+					MinVersion: "1.2",
+				},
+			},
+			expected: nil,
+		},
+		{
+			name: "Client certificate without a key",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "https",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				TLS: TLSConfiguration{
+					CertFile: "../testing/tls/client.pem",
+				},
+			},
+			expected: errors.New("Client certificate without a key"),
+		},
+		{
+			name: "Missing CA bundle",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "https",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				TLS: TLSConfiguration{
+					CAFile: "../testing/tls/missing.pem",
+				},
+			},
+			expected: errors.New("Missing CA bundle"),
+		},
+		{
+			name: "Negative number of retries",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "http",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				Retry:    RetryConfiguration{Retries: -1},
+			},
+			expected: errors.New("Negative number of retries"),
+		},
+		{
+			name: "Valid response format",
+			config: APIConfiguration{
+				Hostname:       "s1.hostname.com",
+				Protocol:       "http",
+				Port:           "2222",
+				Username:       "admin",
+				Token:          "SECRET",
+				ResponseFormat: FormatURLEncoded,
+			},
+			expected: nil,
+		},
+		{
+			name: "Invalid response format",
+			config: APIConfiguration{
+				Hostname:       "s1.hostname.com",
+				Protocol:       "http",
+				Port:           "2222",
+				Username:       "admin",
+				Token:          "SECRET",
+				ResponseFormat: "xml",
+			},
+			expected: errors.New("Invalid response format"),
+		},
+		{
+			name: "Valid REST API",
+			config: APIConfiguration{
+				Hostname:    "s1.hostname.com",
+				Protocol:    "https",
+				Port:        "2222",
+				Username:    "admin",
+				Token:       "SECRET",
+				API:         APIREST,
+				SessionAuth: true,
+			},
+			expected: nil,
+		},
+		{
+			name: "Invalid API",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "https",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				API:      "soap",
+			},
+			expected: errors.New("Invalid API"),
+		},
+		{
+			name: "Session auth with the legacy API",
+			config: APIConfiguration{
+				Hostname:    "s1.hostname.com",
+				Protocol:    "https",
+				Port:        "2222",
+				Username:    "admin",
+				Token:       "SECRET",
+				SessionAuth: true,
+			},
+			expected: errors.New("Session auth with the legacy API"),
+		},
+		{
+			name: "URL-encoded format with the REST API",
+			config: APIConfiguration{
+				Hostname:       "s1.hostname.com",
+				Protocol:       "https",
+				Port:           "2222",
+				Username:       "admin",
+				Token:          "SECRET",
+				API:            APIREST,
+				ResponseFormat: FormatURLEncoded,
+			},
+			expected: errors.New("URL-encoded format with the REST API"),
+		},
+		{
+			name: "Invalid info field",
+			config: APIConfiguration{
+				Hostname:   "s1.hostname.com",
+				Protocol:   "http",
+				Port:       "2222",
+				Username:   "admin",
+				Token:      "SECRET",
+				InfoFields: []string{"client-ip"},
+			},
+			expected: errors.New("Invalid info field"),
+		},
+		{
+			name: "Info field colliding with the hostname label",
+			config: APIConfiguration{
+				Hostname:   "s1.hostname.com",
+				Protocol:   "http",
+				Port:       "2222",
+				Username:   "admin",
+				Token:      "SECRET",
+				InfoFields: []string{"hostname"},
+			},
+			expected: errors.New("Invalid info field"),
+		},
+		{
+			name: "Info field colliding with a target label",
+			config: APIConfiguration{
+				Hostname:   "s1.hostname.com",
+				Protocol:   "http",
+				Port:       "2222",
+				Username:   "admin",
+				Token:      "SECRET",
+				Labels:     map[string]string{"device": "eth0"},
+				InfoFields: []string{"device"},
+			},
+			expected: errors.New("Invalid info field"),
+		},
+		{
+			name: "Invalid user include expression",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "http",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				Users:    UserCollectorConfiguration{Include: "("},
+			},
+			expected: errors.New("Invalid user include expression"),
+		},
+		{
+			name: "Negative max users",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "http",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				Users:    UserCollectorConfiguration{MaxUsers: -1},
+			},
+			expected: errors.New("Negative max users"),
+		},
+		{
+			name: "Target label colliding with the user collector",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "http",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				Labels:   map[string]string{"user": "admin"},
+				Users:    UserCollectorConfiguration{Enabled: true},
+			},
+			expected: errors.New("Label reserved by the user collector"),
+		},
+		{
+			name: "Target label colliding with the reseller collector",
+			config: APIConfiguration{
+				Hostname:  "s1.hostname.com",
+				Protocol:  "http",
+				Port:      "2222",
+				Username:  "admin",
+				Token:     "SECRET",
+				Labels:    map[string]string{"reseller": "admin"},
+				Resellers: ResellerCollectorConfiguration{Enabled: true},
+			},
+			expected: errors.New("Label reserved by the reseller collector"),
+		},
+		{
+			name: "Target label colliding with the domain collector",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "http",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				Labels:   map[string]string{"domain": "example.com"},
+				Domains:  DomainCollectorConfiguration{Enabled: true},
+			},
+			expected: errors.New("Label reserved by the domain collector"),
+		},
+		{
+			name: "Negative domain refresh interval",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "http",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				Domains:  DomainCollectorConfiguration{Interval: -1},
+			},
+			expected: errors.New("Negative domain refresh interval"),
+		},
+		{
+			name: "Target label colliding with the service collector",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "http",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				Labels:   map[string]string{"service": "hosting"},
+				Services: ServiceCollectorConfiguration{Enabled: true},
+			},
+			expected: errors.New("Label reserved by the service collector"),
+		},
+		{
+			name: "Target label colliding with the license collector",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "http",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				Labels:   map[string]string{"type": "production"},
+				License:  LicenseCollectorConfiguration{Enabled: true},
+			},
+			expected: errors.New("Label reserved by the license collector"),
+		},
+		{
+			name: "Target label with the user collector disabled",
+			config: APIConfiguration{
+				Hostname: "s1.hostname.com",
+				Protocol: "http",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				Labels:   map[string]string{"user": "admin"},
+			},
+			expected: nil,
+		},
 		{
 			name: "Missing token",
 			config: APIConfiguration{
@@ -221,118 +519,169 @@ func TestValidateAPIConfiguration(t *testing.T) {
 	}
 }
 
-// APIResponseTest represents a test case for APIRequest function.
-type APIResponseTest struct {
-	Response string
-	Status   int
-}
+// TestValidateAPIConfigurationLabels is a unit test for the validation of
+// the target labels.
+//
+// It validates targets with labels reserved by Prometheus and by
+// the metrics of the exporter. The function verifies that they are
+// rejected, as they would fail every scrape of the target.
+func TestValidateAPIConfigurationLabels(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		expected string
+	}{
+		{
+			name:     "Valid label",
+			labels:   map[string]string{"datacenter": "waw1"},
+			expected: "",
+		},
+		{
+			name:     "Label reserved by Prometheus",
+			labels:   map[string]string{"__x": "y"},
+			expected: `invalid label name "__x"`,
+		},
+		{
+			name:     "Label of the scrape errors",
+			labels:   map[string]string{"reason": "x"},
+			expected: `label "reason" is reserved by a metric of the target`,
+		},
+		{
+			name:   "Label of the filesystems",
+			labels: map[string]string{"mountpoint": "/"},
+			expected: `label "mountpoint" is reserved by a metric of ` +
+				`the target`,
+		},
+		{
+			name:   "Label of the resources",
+			labels: map[string]string{"resource": "x"},
+			expected: `label "resource" is reserved by a metric of ` +
+				`the target`,
+		},
+		{
+			name:     "Kind label of the resources",
+			labels:   map[string]string{"kind": "x"},
+			expected: `label "kind" is reserved by a metric of the target`,
+		},
+		{
+			name:   "Hostname label of the server info",
+			labels: map[string]string{"hostname": "x"},
+			expected: `label "hostname" is reserved by a metric of ` +
+				`the target`,
+		},
+	}
 
-// responseFunction is a helper function to create a response function for
-// HTTP mocking.
-func responseFunction(test APIResponseTest) func(*http.Request) (*http.Response,
-	error) {
-	// Prepare response
-	return func(*http.Request) (*http.Response, error) {
-		if test.Status >= 400 {
-			return httpmock.NewStringResponse(test.Status,
-				""), fmt.Errorf("Error %d", test.Status)
+	// Run tests
+	for _, test := range tests {
+		target := config
+		target.Labels = test.labels
+		err := ValidateAPIConfiguration(target)
+		if test.expected == "" {
+			assert.Nil(t, err, test.name)
+		} else {
+			assert.EqualError(t, err, test.expected, test.name)
 		}
-
-		return httpmock.NewStringResponse(test.Status,
-			test.Response), nil
 	}
 }
 
-// responseFromFile returns the content of a file as a string for HTTP mocking.
-func responseFromFile(file string) string {
-	return httpmock.File(file).String()
-}
-
-// TestAPIRequest tests the APIRequest function.
-func TestAPIRequest(t *testing.T) {
-	// Tests to perform
-	tests := []APIResponseTest{
+// TestValidateAPIConfigurationInfoFields is a unit test for the validation
+// of the info fields of the target.
+//
+// It validates the target configuration with several info fields. The
+// function verifies that the fields which would fail to be exported as
+// labels of the directadmin_server_info metric are rejected.
+func TestValidateAPIConfigurationInfoFields(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		name     string
+		fields   []string
+		labels   map[string]string
+		expected string
+	}{
 		{
-			Response: responseFromFile("../testing/api/successful.json"),
-			Status:   200,
+			name:     "Valid info fields",
+			fields:   []string{"device", "version"},
+			expected: "",
 		},
 		{
-			Response: responseFromFile("../testing/api/invalid-token.json"),
-			Status:   200,
+			name:     "Info field reserved by Prometheus",
+			fields:   []string{"__x"},
+			expected: `invalid info field "__x"`,
 		},
 		{
-			Response: "",
-			Status:   403,
+			name:     "Duplicate info field",
+			fields:   []string{"device", "device"},
+			expected: `duplicate info field "device"`,
 		},
 		{
-			Response: "",
-			Status:   404,
+			name:     "Info field colliding with the hostname label",
+			fields:   []string{"hostname"},
+			expected: `invalid info field "hostname"`,
 		},
 		{
-			Response: "",
-			Status:   500,
+			name:     "Info field colliding with a target label",
+			fields:   []string{"version"},
+			labels:   map[string]string{"version": "x"},
+			expected: `invalid info field "version"`,
 		},
 	}
 
+	// Run tests
 	for _, test := range tests {
-		// Activate HTTP mock
-		httpmock.Activate()
-
-		// Register response
-		httpmock.RegisterResponder("GET", fmt.Sprintf(urlFormat,
-			config.Protocol, config.Username, config.Token, config.Hostname,
-			config.Port), responseFunction(test))
-
-		// Make request
-		response, err := APIRequest(config)
-
-		// Check error
-		if test.Status >= 400 {
-			assert.Error(t, err)
+		target := config
+		target.InfoFields = test.fields
+		target.Labels = test.labels
+		err := ValidateAPIConfiguration(target)
+		if test.expected == "" {
+			assert.Nil(t, err, test.name)
 		} else {
-			assert.Nil(t, err)
+			assert.EqualError(t, err, test.expected, test.name)
 		}
-
-		// Check response
-		assert.Equal(t, test.Response, bytes.NewBuffer(response).String())
-
-		// Deactivate and reset HTTP mock
-		httpmock.DeactivateAndReset()
 	}
 }
 
-// TestAPIRequestIOUtilReadAllError tests the APIRequest function when
-// ioutil.ReadAll returns an error.
-func TestAPIRequestIOUtilReadAllError(t *testing.T) {
-	// mock ioutil.ReadAll()
-	mockIOReadAll = func(io.Reader) ([]byte, error) {
-		return []byte{}, errors.New("faked ioutil.ReadAll() error")
-	}
-	defer func() {
-		mockIOReadAll = io.ReadAll
-	}()
+// APIResponseTest represents a test case for the Client.Request method.
+type APIResponseTest struct {
+	Response string
+	Status   int
+}
 
-	// Activate HTTP mock
-	httpmock.Activate()
-	defer httpmock.DeactivateAndReset()
+// responseFunction is a helper function to create a response function for
+// HTTP mocking.
+func responseFunction(test APIResponseTest) func(*http.Request) (*http.Response,
+	error) {
+	// Prepare response
+	return func(*http.Request) (*http.Response, error) {
+		if test.Status >= 400 {
+			return httpmock.NewStringResponse(test.Status,
+				""), fmt.Errorf("Error %d", test.Status)
+		}
 
-	// Configure response
-	var test = APIResponseTest{
-		Response: "../testing/api/successfull.json",
-		Status:   200,
+		return httpmock.NewStringResponse(test.Status,
+			test.Response), nil
 	}
+}
 
-	// Register response
-	httpmock.RegisterResponder("GET", fmt.Sprintf(urlFormat,
-		config.Protocol, config.Username, config.Token, config.Hostname,
-		config.Port), responseFunction(test))
+// responseFromFile returns the content of a file as a string for HTTP mocking.
+func responseFromFile(file string) string {
+	return httpmock.File(file).String()
+}
 
-	// Make request
-	response, err := APIRequest(config)
+// TestLogError tests that the logError function redacts the token.
+func TestLogError(t *testing.T) {
+	// Capture logs
+	var buffer bytes.Buffer
+	log.SetOutput(&buffer)
+	defer log.SetOutput(os.Stderr)
 
-	// Check error
-	assert.Error(t, err)
+	// Log errors
+	logError(config, fmt.Errorf("Get \"http://admin:%s@localhost\"",
+		config.Token))
+	logError(APIConfiguration{}, errors.New("no token"))
 
-	// Check response
-	assert.Equal(t, "", bytes.NewBuffer(response).String())
+	// Test
+	assert.NotContains(t, buffer.String(), config.Token)
+	assert.Contains(t, buffer.String(), "admin:***@localhost")
+	assert.Contains(t, buffer.String(), "no token")
 }
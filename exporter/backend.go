@@ -0,0 +1,179 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// APIs of DirectAdmin a target can be requested with.
+const (
+	// APILegacy is the API of the CMD_API_* commands.
+	APILegacy = "legacy"
+
+	// APIREST is the JSON REST API under /api/ of newer DirectAdmin
+	// versions.
+	APIREST = "rest"
+)
+
+// backend represents the endpoints of an API of DirectAdmin. The endpoints
+// of a single user or reseller are returned by functions of its name.
+type backend struct {
+	adminStats     string
+	users          string
+	resellers      string
+	services       string
+	license        string
+	userUsage      func(user string) string
+	userConfig     func(user string) string
+	resellerUsage  func(reseller string) string
+	resellerConfig func(reseller string) string
+	resellerUsers  func(reseller string) string
+	userDomains    func(user string) string
+	domainPointers func(domain string) string
+}
+
+// Commands of the legacy API.
+const (
+	commandAdminStats     = "CMD_API_ADMIN_STATS"
+	commandShowAllUsers   = "CMD_API_SHOW_ALL_USERS"
+	commandShowUsers      = "CMD_API_SHOW_USERS"
+	commandShowServices   = "CMD_API_SHOW_SERVICES"
+	commandShowUserUsage  = "CMD_API_SHOW_USER_USAGE"
+	commandShowUserConfig = "CMD_API_SHOW_USER_CONFIG"
+
+	commandShowResellers      = "CMD_API_SHOW_RESELLERS"
+	commandShowResellerUsage  = "CMD_API_SHOW_RESELLER_USAGE"
+	commandShowResellerConfig = "CMD_API_SHOW_RESELLER_CONFIG"
+
+	commandShowUserDomains = "CMD_API_SHOW_USER_DOMAINS"
+	commandDomainPointer   = "CMD_API_DOMAIN_POINTER"
+
+	commandLicense = "CMD_API_LICENSE"
+)
+
+// legacyBackend holds the commands of the legacy API.
+var legacyBackend = backend{
+	adminStats: commandAdminStats,
+	users:      commandShowAllUsers,
+	resellers:  commandShowResellers,
+	services:   commandShowServices,
+	license:    commandLicense,
+	userUsage: func(user string) string {
+		return commandShowUserUsage + "?" + userQuery(user)
+	},
+	userConfig: func(user string) string {
+		return commandShowUserConfig + "?" + userQuery(user)
+	},
+	resellerUsage: func(reseller string) string {
+		return commandShowResellerUsage + "?" + userQuery(reseller)
+	},
+	resellerConfig: func(reseller string) string {
+		return commandShowResellerConfig + "?" + userQuery(reseller)
+	},
+	resellerUsers: func(reseller string) string {
+		return commandShowUsers + "?" +
+			url.Values{"reseller": {reseller}}.Encode()
+	},
+	userDomains: func(user string) string {
+		return commandShowUserDomains + "?" + userQuery(user)
+	},
+	domainPointers: func(domain string) string {
+		return commandDomainPointer + "?" +
+			url.Values{"domain": {domain}}.Encode()
+	},
+}
+
+// restBackend holds the endpoints of the REST API. Their responses have
+// the field names of the legacy commands, so they feed the same metrics.
+var restBackend = backend{
+	adminStats: "api/admin-usage",
+	users:      "api/users",
+	resellers:  "api/resellers",
+	services:   "api/system-services",
+	license:    "api/license",
+	userUsage: func(user string) string {
+		return "api/users/" + url.PathEscape(user) + "/usage"
+	},
+	userConfig: func(user string) string {
+		return "api/users/" + url.PathEscape(user) + "/config"
+	},
+	resellerUsage: func(reseller string) string {
+		return "api/resellers/" + url.PathEscape(reseller) + "/usage"
+	},
+	resellerConfig: func(reseller string) string {
+		return "api/resellers/" + url.PathEscape(reseller) + "/config"
+	},
+	resellerUsers: func(reseller string) string {
+		return "api/resellers/" + url.PathEscape(reseller) + "/users"
+	},
+	userDomains: func(user string) string {
+		return "api/users/" + url.PathEscape(user) + "/domains"
+	},
+	domainPointers: func(domain string) string {
+		return "api/domains/" + url.PathEscape(domain) + "/pointers"
+	},
+}
+
+// userQuery returns the query of the legacy commands of a single user or
+// reseller.
+func userQuery(user string) string {
+	return url.Values{"user": {user}}.Encode()
+}
+
+// restLoginEndpoint is the endpoint of the REST API creating a session.
+const restLoginEndpoint = "api/login"
+
+// backends maps the APIs to their endpoints, the empty API is APILegacy.
+var backends = map[string]backend{
+	"":        legacyBackend,
+	APILegacy: legacyBackend,
+	APIREST:   restBackend,
+}
+
+// authorize adds the credentials to the request. Targets using session auth
+// log in first, once per session.
+func (c *Client) authorize(ctx context.Context, request *http.Request) error {
+	if !c.config.SessionAuth {
+		request.SetBasicAuth(c.config.Username, c.config.Token)
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.session {
+		return nil
+	}
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	c.session = true
+	return nil
+}
+
+// expireSession drops the session, so the next request logs in again.
+func (c *Client) expireSession() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.session = false
+}
+
+// login creates a session of the REST API. The session cookie is kept by
+// the cookie jar of the client.
+func (c *Client) login(ctx context.Context) error {
+	// Prepare a login request
+	body, _ := json.Marshal(map[string]string{
+		"username": c.config.Username,
+		"password": c.config.Token,
+	})
+	// The URL is valid, the request of the command was built from it
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost,
+		commandURL(c.config, restLoginEndpoint), bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+
+	// Perform the login request
+	_, err := c.do(request)
+	return err
+}
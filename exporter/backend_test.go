@@ -0,0 +1,344 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClientBackends tests that the methods of the client request
+// the endpoints of the API of the target.
+func TestClientBackends(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Define configuration
+	rest := config
+	rest.API = APIREST
+
+	// Methods of the user bob, the reseller carol and the domain
+	// example.com
+	userUsage := func(c *Client, ctx context.Context) (
+		map[string]interface{}, error) {
+		return c.UserUsage(ctx, "bob")
+	}
+	userConfig := func(c *Client, ctx context.Context) (
+		map[string]interface{}, error) {
+		return c.UserConfig(ctx, "bob")
+	}
+	resellerUsage := func(c *Client, ctx context.Context) (
+		map[string]interface{}, error) {
+		return c.ResellerUsage(ctx, "carol")
+	}
+	resellerConfig := func(c *Client, ctx context.Context) (
+		map[string]interface{}, error) {
+		return c.ResellerConfig(ctx, "carol")
+	}
+	resellerUsers := func(c *Client, ctx context.Context) (
+		map[string]interface{}, error) {
+		return c.ResellerUsers(ctx, "carol")
+	}
+	userDomains := func(c *Client, ctx context.Context) (
+		map[string]interface{}, error) {
+		return c.UserDomains(ctx, "bob")
+	}
+	domainPointers := func(c *Client, ctx context.Context) (
+		map[string]interface{}, error) {
+		return c.DomainPointers(ctx, "example.com")
+	}
+
+	// Define tests
+	tests := []struct {
+		name   string
+		config APIConfiguration
+		method func(*Client, context.Context) (map[string]interface{},
+			error)
+		url string
+	}{
+		{
+			name:   "Legacy admin stats",
+			config: config,
+			method: (*Client).AdminStats,
+			url:    "http://localhost:2222/CMD_API_ADMIN_STATS?json=yes",
+		},
+		{
+			name:   "Legacy users",
+			config: config,
+			method: (*Client).Users,
+			url:    "http://localhost:2222/CMD_API_SHOW_ALL_USERS?json=yes",
+		},
+		{
+			name:   "Legacy services",
+			config: config,
+			method: (*Client).Services,
+			url:    "http://localhost:2222/CMD_API_SHOW_SERVICES?json=yes",
+		},
+		{
+			name:   "Legacy user usage",
+			config: config,
+			method: userUsage,
+			url: "http://localhost:2222/CMD_API_SHOW_USER_USAGE?user=bob" +
+				"&json=yes",
+		},
+		{
+			name:   "Legacy user config",
+			config: config,
+			method: userConfig,
+			url: "http://localhost:2222/CMD_API_SHOW_USER_CONFIG?user=bob" +
+				"&json=yes",
+		},
+		{
+			name:   "Legacy resellers",
+			config: config,
+			method: (*Client).Resellers,
+			url:    "http://localhost:2222/CMD_API_SHOW_RESELLERS?json=yes",
+		},
+		{
+			name:   "Legacy reseller usage",
+			config: config,
+			method: resellerUsage,
+			url: "http://localhost:2222/CMD_API_SHOW_RESELLER_USAGE" +
+				"?user=carol&json=yes",
+		},
+		{
+			name:   "Legacy reseller config",
+			config: config,
+			method: resellerConfig,
+			url: "http://localhost:2222/CMD_API_SHOW_RESELLER_CONFIG" +
+				"?user=carol&json=yes",
+		},
+		{
+			name:   "Legacy reseller users",
+			config: config,
+			method: resellerUsers,
+			url: "http://localhost:2222/CMD_API_SHOW_USERS" +
+				"?reseller=carol&json=yes",
+		},
+		{
+			name:   "Legacy user domains",
+			config: config,
+			method: userDomains,
+			url: "http://localhost:2222/CMD_API_SHOW_USER_DOMAINS" +
+				"?user=bob&json=yes",
+		},
+		{
+			name:   "Legacy domain pointers",
+			config: config,
+			method: domainPointers,
+			url: "http://localhost:2222/CMD_API_DOMAIN_POINTER" +
+				"?domain=example.com&json=yes",
+		},
+		{
+			name:   "Legacy license",
+			config: config,
+			method: (*Client).License,
+			url:    "http://localhost:2222/CMD_API_LICENSE?json=yes",
+		},
+		{
+			name:   "REST admin stats",
+			config: rest,
+			method: (*Client).AdminStats,
+			url:    "http://localhost:2222/api/admin-usage",
+		},
+		{
+			name:   "REST users",
+			config: rest,
+			method: (*Client).Users,
+			url:    "http://localhost:2222/api/users",
+		},
+		{
+			name:   "REST services",
+			config: rest,
+			method: (*Client).Services,
+			url:    "http://localhost:2222/api/system-services",
+		},
+		{
+			name:   "REST user usage",
+			config: rest,
+			method: userUsage,
+			url:    "http://localhost:2222/api/users/bob/usage",
+		},
+		{
+			name:   "REST user config",
+			config: rest,
+			method: userConfig,
+			url:    "http://localhost:2222/api/users/bob/config",
+		},
+		{
+			name:   "REST resellers",
+			config: rest,
+			method: (*Client).Resellers,
+			url:    "http://localhost:2222/api/resellers",
+		},
+		{
+			name:   "REST reseller usage",
+			config: rest,
+			method: resellerUsage,
+			url:    "http://localhost:2222/api/resellers/carol/usage",
+		},
+		{
+			name:   "REST reseller config",
+			config: rest,
+			method: resellerConfig,
+			url:    "http://localhost:2222/api/resellers/carol/config",
+		},
+		{
+			name:   "REST reseller users",
+			config: rest,
+			method: resellerUsers,
+			url:    "http://localhost:2222/api/resellers/carol/users",
+		},
+		{
+			name:   "REST user domains",
+			config: rest,
+			method: userDomains,
+			url:    "http://localhost:2222/api/users/bob/domains",
+		},
+		{
+			name:   "REST domain pointers",
+			config: rest,
+			method: domainPointers,
+			url:    "http://localhost:2222/api/domains/example.com/pointers",
+		},
+		{
+			name:   "REST license",
+			config: rest,
+			method: (*Client).License,
+			url:    "http://localhost:2222/api/license",
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", test.url,
+			httpmock.NewStringResponder(200, `["admin"]`))
+
+		parsed, err := test.method(NewClient(test.config),
+			context.Background())
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, map[string]interface{}{
+			"list": []interface{}{"admin"},
+		}, parsed, test.name)
+	}
+}
+
+// TestAdminStatsCollectorREST is a unit test for the AdminStatsCollector with
+// a target using the REST API.
+//
+// It registers a response of the REST API with numeric values and updates
+// the collector. The function verifies that the metrics of the legacy API
+// are exported.
+func TestAdminStatsCollectorREST(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "http://localhost:2222/api/admin-usage",
+		httpmock.NewStringResponder(200, `{
+			"usage": {"nusers": 211, "quota": 552070},
+			"allocated": {"quota": 845790, "vdomains": "unlimited"}
+		}`))
+
+	// Update metrics
+	rest := config
+	rest.API = APIREST
+	collector := NewAdminStatsCollector(rest)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Expected metrics
+	expected := `
+# HELP directadmin_nusers Number of users (DirectAdmin field: nusers).
+# TYPE directadmin_nusers gauge
+directadmin_nusers 211
+# HELP directadmin_resource Usage or allocated amount of the resource, in bytes for quota and bandwidth, +Inf if the allocation is unlimited (DirectAdmin fields: usage.<resource>, allocated.<resource>).
+# TYPE directadmin_resource gauge
+directadmin_resource{kind="allocated",resource="quota"} 8.8687509504e+11
+directadmin_resource{kind="allocated",resource="vdomains"} +Inf
+directadmin_resource{kind="usage",resource="quota"} 5.7888735232e+11
+` // nolint: revive
+
+	// Test
+	err := testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_nusers",
+		"directadmin_resource")
+	assert.Nil(t, err)
+}
+
+// sessionServer returns a test server of the REST API with session auth. It
+// counts the logins, and the session is valid until it is reset.
+func sessionServer(t *testing.T, logins *atomic.Int32,
+	session *atomic.Value) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/login", func(w http.ResponseWriter,
+		r *http.Request) {
+		var credentials map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&credentials))
+		if credentials["password"] != config.Token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		logins.Add(1)
+		session.Store("valid")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "valid"})
+	})
+	mux.HandleFunc("GET /api/admin-usage", func(w http.ResponseWriter,
+		r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != session.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"nusers": 211}`))
+	})
+	return httptest.NewServer(mux)
+}
+
+// TestClientSessionAuth tests that the client logs in to the REST API once
+// per session and again when the session expires.
+func TestClientSessionAuth(t *testing.T) {
+	// Start a server with session auth
+	var logins atomic.Int32
+	var session atomic.Value
+	session.Store("")
+	server := sessionServer(t, &logins, &session)
+	defer server.Close()
+
+	// Define configuration
+	target := serverConfiguration(server)
+	target.API = APIREST
+	target.SessionAuth = true
+	client := NewClient(target)
+	client.httpClient.Transport, _ = defaultTransport(target)
+
+	// Requests of a session
+	for i := 0; i < 2; i++ {
+		parsed, err := client.AdminStats(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, float64(211), parsed["nusers"])
+	}
+	assert.Equal(t, int32(1), logins.Load())
+
+	// Request after the session expired
+	session.Store("expired")
+	_, err := client.AdminStats(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), logins.Load())
+
+	// Request with invalid credentials
+	target.Token = "INVALID"
+	client = NewClient(target)
+	client.httpClient.Transport, _ = defaultTransport(target)
+	_, err = client.AdminStats(context.Background())
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	assert.Equal(t, ReasonAuth, ErrorReason(err))
+}
@@ -0,0 +1,51 @@
+package exporter
+
+import "time"
+
+// defaultCircuitCooldown is the time the circuit of a failing target stays
+// open by default.
+const defaultCircuitCooldown = time.Minute
+
+// CircuitBreakerConfiguration represents the circuit breaker settings of
+// a target. The circuit is disabled by default.
+type CircuitBreakerConfiguration struct {
+	// Threshold is the number of consecutive failed requests opening
+	// the circuit.
+	Threshold int `yaml:"threshold" validate:"gte=0"`
+
+	// Cooldown is the time the circuit stays open, the target isn't
+	// requested in the meantime.
+	Cooldown time.Duration `yaml:"cooldown" validate:"gte=0"`
+}
+
+// circuitBreaker stops the requests to a target after consecutive failures.
+// Once the cooldown is over a single request is let through, and its failure
+// opens the circuit again.
+type circuitBreaker struct {
+	config    CircuitBreakerConfiguration
+	failures  int
+	openUntil time.Time
+}
+
+// isOpen reports whether the requests to the target are stopped at
+// the provided time.
+func (b *circuitBreaker) isOpen(now time.Time) bool {
+	return now.Before(b.openUntil)
+}
+
+// record records the result of a request finished at the provided time.
+func (b *circuitBreaker) record(now time.Time, err error) {
+	if err == nil {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.config.Threshold > 0 && b.failures >= b.config.Threshold {
+		cooldown := b.config.Cooldown
+		if cooldown == 0 {
+			cooldown = defaultCircuitCooldown
+		}
+		b.openUntil = now.Add(cooldown)
+	}
+}
@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCircuitBreaker tests the circuitBreaker type.
+func TestCircuitBreaker(t *testing.T) {
+	// Create circuit breaker
+	breaker := &circuitBreaker{config: CircuitBreakerConfiguration{
+		Threshold: 2,
+		Cooldown:  time.Minute,
+	}}
+	now := time.Unix(1688682917, 0)
+	failure := errors.New("refused")
+
+	// Failures below the threshold
+	breaker.record(now, failure)
+	assert.False(t, breaker.isOpen(now))
+	breaker.record(now, nil)
+	breaker.record(now, failure)
+	assert.False(t, breaker.isOpen(now))
+
+	// Failures reaching the threshold
+	breaker.record(now, failure)
+	assert.True(t, breaker.isOpen(now))
+	assert.True(t, breaker.isOpen(now.Add(59*time.Second)))
+	assert.False(t, breaker.isOpen(now.Add(time.Minute)))
+
+	// Failure after the cooldown
+	now = now.Add(time.Minute)
+	breaker.record(now, failure)
+	assert.True(t, breaker.isOpen(now))
+
+	// Success after the cooldown
+	now = now.Add(time.Minute)
+	breaker.record(now, nil)
+	breaker.record(now, failure)
+	assert.False(t, breaker.isOpen(now))
+}
+
+// TestCircuitBreakerDefaults tests the default settings of
+// the circuitBreaker type.
+func TestCircuitBreakerDefaults(t *testing.T) {
+	now := time.Unix(1688682917, 0)
+	failure := errors.New("refused")
+
+	// Disabled circuit breaker
+	disabled := &circuitBreaker{}
+	for i := 0; i < 10; i++ {
+		disabled.record(now, failure)
+	}
+	assert.False(t, disabled.isOpen(now))
+
+	// Default cooldown
+	breaker := &circuitBreaker{config: CircuitBreakerConfiguration{
+		Threshold: 1,
+	}}
+	breaker.record(now, failure)
+	assert.True(t, breaker.isOpen(now.Add(defaultCircuitCooldown-1)))
+	assert.False(t, breaker.isOpen(now.Add(defaultCircuitCooldown)))
+}
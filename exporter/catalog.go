@@ -0,0 +1,149 @@
+package exporter
+
+import (
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// megabyte is the unit DirectAdmin reports quota and bandwidth in.
+const megabyte = 1024 * 1024
+
+// metricDefinition describes a metric exported from a usage field of
+// the CMD_API_ADMIN_STATS response. Values are multiplied by scale to
+// convert them to base units, a zero scale leaves them unchanged.
+type metricDefinition struct {
+	field     string
+	name      string
+	help      string
+	valueType prometheus.ValueType
+	scale     float64
+}
+
+// convert returns the value converted to the base unit of the metric.
+func (m metricDefinition) convert(value float64) float64 {
+	if m.scale == 0 {
+		return value
+	}
+	return value * m.scale
+}
+
+// resourceScales holds the scales converting the resources reported in
+// megabytes to bytes.
+var resourceScales = map[string]float64{
+	"bandwidth": megabyte,
+	"quota":     megabyte,
+}
+
+// serverMetrics is the catalog of the metrics exported from the usage
+// fields, in the order they are exported. Bandwidth and email deliveries
+// grow during the month and are reset to zero by the monthly tally, so they
+// are exported as counters.
+var serverMetrics = []metricDefinition{
+	{
+		field: "bandwidth",
+		name:  "bandwidth_bytes_total",
+		help: "Bandwidth used in the current tally period in bytes, " +
+			"reset by the monthly tally (DirectAdmin field: bandwidth).",
+		valueType: prometheus.CounterValue,
+		scale:     megabyte,
+	},
+	{
+		field: "db_quota",
+		name:  "db_quota_bytes",
+		help: "Disk space used by databases in bytes " +
+			"(DirectAdmin field: db_quota).",
+		valueType: prometheus.GaugeValue,
+	},
+	{
+		field: "email_deliveries",
+		name:  "email_deliveries_total",
+		help: "Email deliveries in the current tally period, reset by " +
+			"the monthly tally (DirectAdmin field: email_deliveries).",
+		valueType: prometheus.CounterValue,
+	},
+	{
+		field: "email_deliveries_incoming",
+		name:  "email_deliveries_incoming_total",
+		help: "Incoming email deliveries in the current tally period, " +
+			"reset by the monthly tally " +
+			"(DirectAdmin field: email_deliveries_incoming).",
+		valueType: prometheus.CounterValue,
+	},
+	{
+		field: "email_deliveries_outgoing",
+		name:  "email_deliveries_outgoing_total",
+		help: "Outgoing email deliveries in the current tally period, " +
+			"reset by the monthly tally " +
+			"(DirectAdmin field: email_deliveries_outgoing).",
+		valueType: prometheus.CounterValue,
+	},
+	{
+		field: "email_quota",
+		name:  "email_quota_bytes",
+		help: "Disk space used by email accounts in bytes " +
+			"(DirectAdmin field: email_quota).",
+		valueType: prometheus.GaugeValue,
+	},
+	{
+		field: "last_tally",
+		name:  "last_tally_timestamp_seconds",
+		help: "Unix time of the last tally " +
+			"(DirectAdmin field: last_tally).",
+		valueType: prometheus.GaugeValue,
+	},
+	{
+		field:     "nresellers",
+		name:      "nresellers",
+		help:      "Number of resellers (DirectAdmin field: nresellers).",
+		valueType: prometheus.GaugeValue,
+	},
+	{
+		field:     "nusers",
+		name:      "nusers",
+		help:      "Number of users (DirectAdmin field: nusers).",
+		valueType: prometheus.GaugeValue,
+	},
+	{
+		field: "other_quota",
+		name:  "other_quota_bytes",
+		help: "Disk space used by other files in bytes " +
+			"(DirectAdmin field: other_quota).",
+		valueType: prometheus.GaugeValue,
+	},
+}
+
+// resourceScale returns the scale converting the resource to its base
+// unit.
+func resourceScale(resource string) float64 {
+	if scale, exists := resourceScales[resource]; exists {
+		return scale
+	}
+	return 1
+}
+
+// usageFields lists all the usage fields of the CMD_API_ADMIN_STATS
+// response read by the AdminStats schema.
+var usageFields = catalogFields()
+
+// catalogFields returns the usage fields of the resources and of
+// the server metrics catalog.
+func catalogFields() []string {
+	fields := slices.Clone(resourceFields)
+	for _, metric := range serverMetrics {
+		if !slices.Contains(fields, metric.field) {
+			fields = append(fields, metric.field)
+		}
+	}
+	return fields
+}
+
+// tallyPeriodStart returns the start of the monthly tally period the last
+// tally belongs to. The exporter doesn't know the time zone of the target,
+// so the period starts on the first day of the month in UTC.
+func tallyPeriodStart(lastTally float64) time.Time {
+	tally := time.Unix(int64(lastTally), 0).UTC()
+	return time.Date(tally.Year(), tally.Month(), 1, 0, 0, 0, 0,
+		time.UTC)
+}
@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCatalogFields tests the catalogFields function.
+func TestCatalogFields(t *testing.T) {
+	// Get fields
+	fields := catalogFields()
+
+	// Test
+	count := 0
+	for _, field := range fields {
+		if field == "bandwidth" {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+	assert.Contains(t, fields, "email_deliveries_incoming")
+	assert.Contains(t, fields, "vdomains")
+}
+
+// TestTallyPeriodStart tests the tallyPeriodStart function.
+func TestTallyPeriodStart(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		given    time.Time
+		expected time.Time
+	}{
+		{
+			given:    time.Date(2023, 7, 6, 22, 35, 17, 0, time.UTC),
+			expected: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			given:    time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			given: time.Date(2023, 7, 31, 23, 30, 0, 0,
+				time.FixedZone("UTC-2", -2*60*60)),
+			expected: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	// Perform tests
+	for _, test := range tests {
+		given := tallyPeriodStart(float64(test.given.Unix()))
+		assert.True(t, test.expected.Equal(given), given)
+	}
+}
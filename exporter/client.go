@@ -0,0 +1,335 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+	"time"
+)
+
+var urlFormat = "%s://%s:%s/%s"
+var mockIOReadAll = io.ReadAll
+
+// keepAlive is the interval between the keep-alive probes of the connections
+// to the DirectAdmin API.
+const keepAlive = 30 * time.Second
+
+// idleConnTimeout is the time an idle connection to the DirectAdmin API is
+// kept open for reuse.
+const idleConnTimeout = 90 * time.Second
+
+// Client is a client of the DirectAdmin API built from an APIConfiguration.
+// It owns its HTTP client, so the connections to the target are kept alive
+// and reused between requests.
+type Client struct {
+	config     APIConfiguration
+	backend    backend
+	httpClient *http.Client
+
+	// err is the error of building the transport, returned by every request
+	err error
+
+	// session reports whether the client is logged in, for the targets
+	// using session auth
+	mutex   sync.Mutex
+	session bool
+}
+
+// NewClient returns a new Client for the provided configuration. Targets
+// using session auth keep the session cookie in a cookie jar.
+func NewClient(config APIConfiguration) *Client {
+	transport, err := newTransport(config)
+	client := &Client{
+		config:  config,
+		backend: backends[config.API],
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+		},
+		err: err,
+	}
+	if config.SessionAuth {
+		client.httpClient.Jar, _ = cookiejar.New(nil)
+	}
+	return client
+}
+
+// newTransport returns the HTTP transport of a client, it is replaced in
+// tests.
+var newTransport = defaultTransport
+
+// defaultTransport returns an HTTP transport with the connect and read
+// timeouts and the TLS settings of the target.
+func defaultTransport(config APIConfiguration) (http.RoundTripper, error) {
+	tlsConfig, err := newTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: config.ConnectTimeout, KeepAlive: keepAlive}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   config.ConnectTimeout,
+		ResponseHeaderTimeout: config.ReadTimeout,
+		TLSClientConfig:       tlsConfig,
+	}, nil
+}
+
+// commandURL returns the URL of the API command. It doesn't contain
+// the credentials, which are sent in the Authorization header. JSON is
+// requested from the legacy API unless the target uses the URL-encoded
+// format.
+func commandURL(config APIConfiguration, command string) string {
+	url := fmt.Sprintf(urlFormat, config.Protocol, config.Hostname,
+		config.Port, command)
+	if config.API != APIREST && config.ResponseFormat != FormatURLEncoded {
+		separator := "?"
+		if strings.Contains(command, "?") {
+			separator = "&"
+		}
+		url += separator + "json=yes"
+	}
+	return url
+}
+
+// Response represents a response of the DirectAdmin API.
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Request performs a request of the API command, or the endpoint of
+// the REST API, and returns the response. The credentials are sent with
+// basic authentication, or in the session cookie. Statuses of 400 and above
+// are returned as errors. Temporary failures are retried with the retry
+// settings of the target.
+func (c *Client) Request(ctx context.Context, command string) (Response,
+	error) {
+	response, err := c.request(ctx, command)
+
+	// Log in again once when the session expired
+	if c.config.SessionAuth && errors.Is(err, ErrUnauthorized) {
+		response, err = c.request(ctx, command)
+	}
+
+	retry := c.config.Retry
+	for attempt := 0; attempt < retry.Retries && isTemporary(err); attempt++ {
+		if sleep(ctx, retry.backoff(attempt)) != nil {
+			break
+		}
+		response, err = c.request(ctx, command)
+	}
+	return response, err
+}
+
+// request performs a single request of the API command.
+func (c *Client) request(ctx context.Context, command string) (Response,
+	error) {
+	// Check the transport
+	if c.err != nil {
+		logError(c.config, c.err)
+		return Response{}, newScrapeError(ReasonNetwork, c.err)
+	}
+
+	// Prepare a request to the DirectAdmin API
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		commandURL(c.config, command), nil)
+	if err != nil {
+		logError(c.config, err)
+		return Response{}, newScrapeError(ReasonNetwork, err)
+	}
+	if err := c.authorize(ctx, request); err != nil {
+		return Response{}, err
+	}
+
+	// Perform a request to the DirectAdmin API
+	response, err := c.do(request)
+	if errors.Is(err, ErrUnauthorized) {
+		c.expireSession()
+	}
+	return response, err
+}
+
+// do performs the request and reads the response.
+func (c *Client) do(request *http.Request) (Response, error) {
+	// Perform a request to the DirectAdmin API
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		logError(c.config, err)
+		return Response{}, newScrapeError(ReasonNetwork, err)
+	}
+	defer resp.Body.Close()
+
+	// Read the response body
+	body, err := mockIOReadAll(resp.Body)
+	if err != nil {
+		logError(c.config, err)
+		return Response{}, newScrapeError(ReasonNetwork, err)
+	}
+	response := Response{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}
+
+	// Check the response status
+	if err := statusError(response); err != nil {
+		logError(c.config, err)
+		return Response{}, err
+	}
+
+	// Return the response
+	return response, nil
+}
+
+// statusError returns the error of the response status, or nil if
+// the request succeeded.
+func statusError(response Response) error {
+	switch {
+	case response.StatusCode == http.StatusUnauthorized ||
+		response.StatusCode == http.StatusForbidden:
+		return newResponseError(ErrUnauthorized, response)
+	case response.StatusCode >= http.StatusBadRequest:
+		return newResponseError(ErrHTTPStatus, response)
+	}
+	return nil
+}
+
+// AdminStats returns the parsed server statistics, the response of
+// the CMD_API_ADMIN_STATS command of the legacy API.
+func (c *Client) AdminStats(ctx context.Context) (map[string]interface{},
+	error) {
+	return c.command(ctx, c.backend.adminStats)
+}
+
+// Users returns the parsed list of the users, the response of
+// the CMD_API_SHOW_ALL_USERS command of the legacy API.
+func (c *Client) Users(ctx context.Context) (map[string]interface{},
+	error) {
+	return c.command(ctx, c.backend.users)
+}
+
+// UserUsage returns the parsed usage of the user, the response of
+// the CMD_API_SHOW_USER_USAGE command of the legacy API.
+func (c *Client) UserUsage(ctx context.Context,
+	user string) (map[string]interface{}, error) {
+	return c.command(ctx, c.backend.userUsage(user))
+}
+
+// UserConfig returns the parsed configuration and limits of the user,
+// the response of the CMD_API_SHOW_USER_CONFIG command of the legacy API.
+func (c *Client) UserConfig(ctx context.Context,
+	user string) (map[string]interface{}, error) {
+	return c.command(ctx, c.backend.userConfig(user))
+}
+
+// Resellers returns the parsed list of the resellers, the response of
+// the CMD_API_SHOW_RESELLERS command of the legacy API.
+func (c *Client) Resellers(ctx context.Context) (map[string]interface{},
+	error) {
+	return c.command(ctx, c.backend.resellers)
+}
+
+// ResellerUsage returns the parsed usage of the reseller, the response of
+// the CMD_API_SHOW_RESELLER_USAGE command of the legacy API.
+func (c *Client) ResellerUsage(ctx context.Context,
+	reseller string) (map[string]interface{}, error) {
+	return c.command(ctx, c.backend.resellerUsage(reseller))
+}
+
+// ResellerConfig returns the parsed configuration and limits of
+// the reseller, the response of the CMD_API_SHOW_RESELLER_CONFIG command of
+// the legacy API.
+func (c *Client) ResellerConfig(ctx context.Context,
+	reseller string) (map[string]interface{}, error) {
+	return c.command(ctx, c.backend.resellerConfig(reseller))
+}
+
+// ResellerUsers returns the parsed list of the users created by
+// the reseller, the response of the CMD_API_SHOW_USERS command of the legacy
+// API.
+func (c *Client) ResellerUsers(ctx context.Context,
+	reseller string) (map[string]interface{}, error) {
+	return c.command(ctx, c.backend.resellerUsers(reseller))
+}
+
+// UserDomains returns the parsed domains of the user, the response of
+// the CMD_API_SHOW_USER_DOMAINS command of the legacy API.
+func (c *Client) UserDomains(ctx context.Context,
+	user string) (map[string]interface{}, error) {
+	return c.command(ctx, c.backend.userDomains(user))
+}
+
+// DomainPointers returns the parsed pointers of the domain, the response of
+// the CMD_API_DOMAIN_POINTER command of the legacy API.
+func (c *Client) DomainPointers(ctx context.Context,
+	domain string) (map[string]interface{}, error) {
+	return c.command(ctx, c.backend.domainPointers(domain))
+}
+
+// Services returns the parsed states of the services, the response of
+// the CMD_API_SHOW_SERVICES command of the legacy API.
+func (c *Client) Services(ctx context.Context) (map[string]interface{},
+	error) {
+	return c.command(ctx, c.backend.services)
+}
+
+// License returns the parsed license of the server, the response of
+// the CMD_API_LICENSE command of the legacy API.
+func (c *Client) License(ctx context.Context) (map[string]interface{},
+	error) {
+	return c.command(ctx, c.backend.license)
+}
+
+// command performs a request of the API command and parses its response.
+func (c *Client) command(ctx context.Context,
+	command string) (map[string]interface{}, error) {
+	// Perform API Request
+	response, err := c.Request(ctx, command)
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	// Parse response
+	parser := responseParsers[responseFormat(c.config.ResponseFormat,
+		response)]
+	parsed, err := parser.parse(response.Body)
+	if err != nil {
+		err := newResponseError(parser.err, response)
+		logError(c.config, err)
+		return map[string]interface{}{}, err
+	}
+
+	// Handle API errors, the legacy format reports success as error=0
+	if parsed["error"] != nil && parsed["error"] != "0" {
+		err := newResponseError(apiError(parsed), response)
+		logError(c.config, err)
+		return map[string]interface{}{}, err
+	}
+
+	return parsed, nil
+}
+
+// apiError returns the error of the API error field of the parsed response,
+// ErrUnauthorized if it reports the request as not logged in, such as with
+// an invalid token, or ErrAPIError otherwise. The legacy format reports
+// the message in the text field.
+func apiError(parsed map[string]interface{}) error {
+	for _, field := range []string{"error", "text"} {
+		message, _ := parsed[field].(string)
+		if strings.Contains(strings.ToLower(message), "not logged in") {
+			return ErrUnauthorized
+		}
+	}
+	return ErrAPIError
+}
@@ -0,0 +1,552 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// serverConfiguration returns the configuration of a target served by
+// the test server.
+func serverConfiguration(server *httptest.Server) APIConfiguration {
+	protocol, address, _ := strings.Cut(server.URL, "://")
+	target := config
+	target.Protocol = protocol
+	target.Hostname, target.Port, _ = net.SplitHostPort(address)
+	return target
+}
+
+// TestMain replaces the transport of the clients. A nil transport makes them
+// use http.DefaultTransport, which is replaced by httpmock.
+func TestMain(m *testing.M) {
+	newTransport = func(APIConfiguration) (http.RoundTripper, error) {
+		return nil, nil
+	}
+	os.Exit(m.Run())
+}
+
+// statsURL returns the URL of the CMD_API_ADMIN_STATS command of the target.
+func statsURL(config APIConfiguration) string {
+	return commandURL(config, commandAdminStats)
+}
+
+// TestClientRequest tests the Client.Request method.
+func TestClientRequest(t *testing.T) {
+	// Tests to perform
+	tests := []APIResponseTest{
+		{
+			Response: responseFromFile("../testing/api/successful.json"),
+			Status:   200,
+		},
+		{
+			Response: responseFromFile("../testing/api/invalid-token.json"),
+			Status:   200,
+		},
+		{
+			Response: "",
+			Status:   403,
+		},
+		{
+			Response: "",
+			Status:   404,
+		},
+		{
+			Response: "",
+			Status:   500,
+		},
+	}
+
+	for _, test := range tests {
+		// Activate HTTP mock
+		httpmock.Activate()
+
+		// Register response
+		httpmock.RegisterResponder("GET", statsURL(config), responseFunction(test))
+
+		// Make request
+		response, err := NewClient(config).Request(context.Background(),
+			commandAdminStats)
+
+		// Check error
+		if test.Status >= 400 {
+			assert.Error(t, err)
+		} else {
+			assert.Nil(t, err)
+		}
+
+		// Check response
+		assert.Equal(t, test.Response, bytes.NewBuffer(response.Body).String())
+
+		// Deactivate and reset HTTP mock
+		httpmock.DeactivateAndReset()
+	}
+}
+
+// TestClientRequestIOUtilReadAllError tests the Client.Request method when
+// ioutil.ReadAll returns an error.
+func TestClientRequestIOUtilReadAllError(t *testing.T) {
+	// mock ioutil.ReadAll()
+	mockIOReadAll = func(io.Reader) ([]byte, error) {
+		return []byte{}, errors.New("faked ioutil.ReadAll() error")
+	}
+	defer func() {
+		mockIOReadAll = io.ReadAll
+	}()
+
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Configure response
+	var test = APIResponseTest{
+		Response: "../testing/api/successfull.json",
+		Status:   200,
+	}
+
+	// Register response
+	httpmock.RegisterResponder("GET", statsURL(config), responseFunction(test))
+
+	// Make request
+	response, err := NewClient(config).Request(context.Background(),
+		commandAdminStats)
+
+	// Check error
+	assert.Error(t, err)
+
+	// Check response
+	assert.Equal(t, "", bytes.NewBuffer(response.Body).String())
+}
+
+// TestClientRequestBasicAuth tests that the Client.Request method sends
+// the credentials in the Authorization header and not in the URL.
+func TestClientRequestBasicAuth(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register response checking the credentials
+	httpmock.RegisterResponder("GET", statsURL(config),
+		func(request *http.Request) (*http.Response, error) {
+			username, password, ok := request.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, config.Username, username)
+			assert.Equal(t, config.Token, password)
+			assert.Nil(t, request.URL.User)
+			return httpmock.NewStringResponse(200, "{}"), nil
+		})
+
+	// Make request
+	response, err := NewClient(config).Request(context.Background(),
+		commandAdminStats)
+
+	// Test
+	assert.Nil(t, err)
+	assert.Equal(t, "{}", string(response.Body))
+	assert.NotContains(t, statsURL(config), config.Token)
+}
+
+// TestClientRequestInvalidURL tests the Client.Request method when the request
+// cannot be created.
+func TestClientRequestInvalidURL(t *testing.T) {
+	// Define invalid configuration
+	invalid := config
+	invalid.Hostname = "local host"
+
+	// Make request
+	response, err := NewClient(invalid).Request(context.Background(),
+		commandAdminStats)
+
+	// Test
+	assert.Equal(t, ReasonNetwork, ErrorReason(err))
+	assert.Empty(t, response)
+}
+
+// TestClientRequestCanceled tests that the Client.Request method stops when
+// the context is canceled.
+func TestClientRequestCanceled(t *testing.T) {
+	// Start a server answering after the request is canceled
+	server := httptest.NewServer(http.HandlerFunc(
+		func(_ http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+	defer server.Close()
+	client := NewClient(serverConfiguration(server))
+	client.httpClient.Transport, _ = defaultTransport(client.config)
+
+	// Make request with a context canceled during the request
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	response, err := client.Request(ctx, commandAdminStats)
+
+	// Test
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, ReasonNetwork, ErrorReason(err))
+	assert.Empty(t, response)
+}
+
+// TestClientAdminStats tests that the Client.AdminStats method returns
+// the parsed response of the CMD_API_ADMIN_STATS command.
+func TestClientAdminStats(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register response
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200, `{"nusers":"3"}`))
+
+	// Make request
+	response, err := NewClient(config).AdminStats(context.Background())
+
+	// Test
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"nusers": "3"}, response)
+}
+
+// TestDefaultTransport tests that the default transport applies the connect
+// and read timeouts of the target.
+func TestDefaultTransport(t *testing.T) {
+	// Define configuration
+	timeouts := config
+	timeouts.ConnectTimeout = 2 * time.Second
+	timeouts.ReadTimeout = 5 * time.Second
+
+	// Create transport
+	roundTripper, err := defaultTransport(timeouts)
+	transport, ok := roundTripper.(*http.Transport)
+
+	// Test
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, transport.TLSHandshakeTimeout)
+	assert.Equal(t, 5*time.Second, transport.ResponseHeaderTimeout)
+	assert.Equal(t, idleConnTimeout, transport.IdleConnTimeout)
+	assert.False(t, transport.DisableKeepAlives)
+}
+
+// TestClientReusesConnections tests that the client keeps the connection to
+// the target alive between requests.
+func TestClientReusesConnections(t *testing.T) {
+	// Start a server counting the connections
+	var connections atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("{}"))
+		}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	// Make requests
+	client := NewClient(serverConfiguration(server))
+	client.httpClient.Transport, _ = defaultTransport(client.config)
+	for i := 0; i < 3; i++ {
+		_, err := client.Request(context.Background(), commandAdminStats)
+		assert.Nil(t, err)
+	}
+
+	// Test
+	assert.Equal(t, int32(1), connections.Load())
+}
+
+// TestDefaultTransportInvalidTLS tests that the defaultTransport function
+// returns the error of the TLS settings.
+func TestDefaultTransportInvalidTLS(t *testing.T) {
+	// Define configuration
+	invalid := config
+	invalid.TLS.MinVersion = "1.4"
+
+	// Create transport
+	transport, err := defaultTransport(invalid)
+
+	// Test
+	assert.Error(t, err)
+	assert.Nil(t, transport)
+}
+
+// TestClientRequestInvalidTransport tests that the Client.Request method
+// returns the error of building the transport.
+func TestClientRequestInvalidTransport(t *testing.T) {
+	// Replace transport
+	defer func(original func(APIConfiguration) (http.RoundTripper,
+		error)) {
+		newTransport = original
+	}(newTransport)
+	newTransport = defaultTransport
+
+	// Define configuration
+	invalid := config
+	invalid.TLS.CAFile = "../testing/tls/missing.pem"
+
+	// Make request
+	response, err := NewClient(invalid).Request(context.Background(),
+		commandAdminStats)
+
+	// Test
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, ReasonNetwork, ErrorReason(err))
+	assert.Empty(t, response)
+}
+
+// TestClientRequestTLS tests the Client.Request method with the TLS settings
+// of a server using a self-signed certificate.
+func TestClientRequestTLS(t *testing.T) {
+	// Start a TLS server
+	server := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("{}"))
+		}))
+	defer server.Close()
+
+	// Write the certificate of the server as a CA bundle
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{
+		Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600)
+	assert.Nil(t, err)
+
+	// Define tests
+	tests := []struct {
+		name  string
+		tls   TLSConfiguration
+		valid bool
+	}{
+		{
+			name:  "Unknown certificate authority",
+			tls:   TLSConfiguration{},
+			valid: false,
+		},
+		{
+			name:  "CA bundle",
+			tls:   TLSConfiguration{CAFile: caFile},
+			valid: true,
+		},
+		{
+			name:  "CA bundle with another server name",
+			tls:   TLSConfiguration{CAFile: caFile, ServerName: "other"},
+			valid: false,
+		},
+		{
+			name:  "CA bundle with server name override",
+			tls:   TLSConfiguration{CAFile: caFile, ServerName: "example.com"},
+			valid: true,
+		},
+		{
+			name:  "Insecure skip verify",
+			tls:   TLSConfiguration{InsecureSkipVerify: true},
+			valid: true,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		target := serverConfiguration(server)
+		target.TLS = test.tls
+		client := NewClient(target)
+		client.httpClient.Transport, _ = defaultTransport(target)
+
+		_, err := client.Request(context.Background(), commandAdminStats)
+		if test.valid {
+			assert.Nil(t, err, test.name)
+		} else {
+			assert.Error(t, err, test.name)
+		}
+	}
+}
+
+// TestClientRequestRetries tests that the Client.Request method retries
+// the temporary failures only.
+func TestClientRequestRetries(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Define configuration
+	retrying := config
+	retrying.Retry = RetryConfiguration{Retries: 2, Backoff: time.Millisecond}
+
+	// Define tests
+	tests := []struct {
+		name      string
+		responder httpmock.Responder
+		calls     int
+		valid     bool
+	}{
+		{
+			name: "Temporary failure",
+			responder: httpmock.NewStringResponder(503, "").Then(
+				httpmock.NewStringResponder(200, "{}")),
+			calls: 2,
+			valid: true,
+		},
+		{
+			name:      "Persistent temporary failure",
+			responder: httpmock.NewStringResponder(502, ""),
+			calls:     3,
+			valid:     false,
+		},
+		{
+			name:      "Other failure",
+			responder: httpmock.NewStringResponder(500, ""),
+			calls:     1,
+			valid:     false,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", statsURL(retrying), test.responder)
+
+		_, err := NewClient(retrying).Request(context.Background(),
+			commandAdminStats)
+		assert.Equal(t, test.valid, err == nil, test.name)
+		assert.Equal(t, test.calls, httpmock.GetTotalCallCount(), test.name)
+	}
+}
+
+// TestClientRequestRetriesCanceled tests that the Client.Request method
+// stops retrying when the context is done.
+func TestClientRequestRetriesCanceled(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(503, ""))
+
+	// Define configuration
+	retrying := config
+	retrying.Retry = RetryConfiguration{Retries: 5, Backoff: time.Hour}
+
+	// Make request with a context done during the backoff
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	_, err := NewClient(retrying).Request(ctx, commandAdminStats)
+
+	// Test
+	assert.ErrorIs(t, err, ErrHTTPStatus)
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
+
+// TestClientAdminStatsURLEncoded tests that the Client.AdminStats method
+// parses the responses of the legacy URL-encoded format.
+func TestClientAdminStatsURLEncoded(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Define configurations
+	legacy := config
+	legacy.ResponseFormat = FormatURLEncoded
+
+	// Define tests
+	tests := []struct {
+		name     string
+		config   APIConfiguration
+		response *http.Response
+		reason   string
+	}{
+		{
+			name:     "Format chosen from the Content-Type",
+			config:   config,
+			response: textResponse(200, "text/plain", "nusers=211"),
+		},
+		{
+			name:     "Format of the target",
+			config:   legacy,
+			response: textResponse(200, "text/html", "nusers=211"),
+		},
+		{
+			name:     "Legacy success",
+			config:   legacy,
+			response: textResponse(200, "", "error=0&nusers=211"),
+		},
+		{
+			name:   "Legacy error",
+			config: legacy,
+			response: textResponse(200, "",
+				"error=1&text=Cannot+show+the+stats"),
+			reason: ReasonAPI,
+		},
+		{
+			name:   "Legacy not logged in",
+			config: legacy,
+			response: textResponse(200, "",
+				"error=1&text=Not+logged+in"),
+			reason: ReasonAuth,
+		},
+		{
+			name:     "Invalid response",
+			config:   legacy,
+			response: textResponse(200, "", "nusers=%zz"),
+			reason:   ReasonParse,
+		},
+		{
+			name:   "Login page",
+			config: legacy,
+			response: textResponse(200, "text/html",
+				"<html><body><form action=\"/CMD_LOGIN\">"+
+					"</form></body></html>"),
+			reason: ReasonParse,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", statsURL(test.config),
+			httpmock.ResponderFromResponse(test.response))
+
+		parsed, err := NewClient(test.config).AdminStats(
+			context.Background())
+		assert.Equal(t, test.reason, ErrorReason(err), test.name)
+		if test.reason == "" {
+			assert.Equal(t, "211", parsed["nusers"], test.name)
+		}
+	}
+}
+
+// TestCommandURL tests that JSON is not requested from the targets using
+// the legacy URL-encoded format or the REST API.
+func TestCommandURL(t *testing.T) {
+	// Define configurations
+	legacy := config
+	legacy.ResponseFormat = FormatURLEncoded
+	rest := config
+	rest.API = APIREST
+
+	// Test
+	assert.Equal(t, "http://localhost:2222/CMD_API_ADMIN_STATS?json=yes",
+		commandURL(config, commandAdminStats))
+	assert.Equal(t, "http://localhost:2222/CMD_API_ADMIN_STATS",
+		commandURL(legacy, commandAdminStats))
+	assert.Equal(t, "http://localhost:2222/api/admin-usage",
+		commandURL(rest, restBackend.adminStats))
+}
+
+// textResponse returns a response with the body and the Content-Type.
+func textResponse(status int, contentType string,
+	body string) *http.Response {
+	response := httpmock.NewStringResponse(status, body)
+	response.Header.Set("Content-Type", contentType)
+	return response
+}
@@ -0,0 +1,90 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+)
+
+// Configuration represents the content of the YAML configuration file.
+type Configuration struct {
+	Targets []APIConfiguration `yaml:"targets"`
+}
+
+// LoadConfiguration reads and validates the YAML configuration file.
+func LoadConfiguration(filename string) (Configuration, error) {
+	var configuration Configuration
+
+	// Read configuration file
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return configuration, err
+	}
+
+	// Decode configuration
+	if err := yaml.Unmarshal(content, &configuration); err != nil {
+		return configuration, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return configuration, ValidateConfiguration(configuration)
+}
+
+// ValidateConfiguration validates every target of the configuration with
+// the rules used by ValidateAPIConfiguration. Target names are required and
+// have to be unique.
+func ValidateConfiguration(configuration Configuration) error {
+	if len(configuration.Targets) == 0 {
+		return errors.New("no targets configured")
+	}
+
+	names := make(map[string]bool)
+	for i, target := range configuration.Targets {
+		// Check target name
+		if target.Name == "" {
+			return fmt.Errorf("target #%d: missing name", i+1)
+		}
+		if names[target.Name] {
+			return fmt.Errorf("target %q: duplicated name", target.Name)
+		}
+		names[target.Name] = true
+
+		// Check target fields
+		if err := validateTarget(target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TargetsByName returns the targets of the configuration indexed by their
+// names.
+func (c Configuration) TargetsByName() map[string]APIConfiguration {
+	targets := make(map[string]APIConfiguration)
+	for _, target := range c.Targets {
+		targets[target.Name] = target
+	}
+	return targets
+}
+
+// validateTarget validates a single target and names the target and the
+// field that failed in the returned error.
+func validateTarget(target APIConfiguration) error {
+	err := ValidateAPIConfiguration(target)
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		// Drop the struct name, the namespace holds the YAML keys
+		field := validationErrors[0]
+		_, path, _ := strings.Cut(field.Namespace(), ".")
+		return fmt.Errorf("target %q: field %s failed on the %q rule",
+			target.Name, path, field.Tag())
+	}
+	if err != nil {
+		return fmt.Errorf("target %q: %w", target.Name, err)
+	}
+	return nil
+}
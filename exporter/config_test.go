@@ -0,0 +1,130 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoadConfiguration tests the LoadConfiguration function.
+func TestLoadConfiguration(t *testing.T) {
+	// Load configuration file
+	given, err := LoadConfiguration("../testing/config/valid.yml")
+
+	// Expected result
+	expected := Configuration{
+		Targets: []APIConfiguration{
+			{
+				Name:     "server1",
+				Hostname: "localhost",
+				Protocol: "http",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+				Timeout:  5 * time.Second,
+				Labels:   map[string]string{"datacenter": "waw1"},
+			},
+			{
+				Name:     "server2",
+				Hostname: "127.0.0.1",
+				Protocol: "https",
+				Port:     "2222",
+				Username: "admin",
+				Token:    "SECRET",
+			},
+		},
+	}
+
+	// Test
+	assert.Nil(t, err)
+	assert.Equal(t, expected, given)
+	assert.Equal(t, expected.Targets[1],
+		given.TargetsByName()["server2"])
+}
+
+// TestLoadConfigurationErrors tests the LoadConfiguration function with
+// missing, malformed and invalid files.
+func TestLoadConfigurationErrors(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		filename string
+		expected string
+	}{
+		{
+			filename: "../testing/config/non-existing-file.yml",
+			expected: "no such file or directory",
+		},
+		{
+			filename: "../testing/config/invalid-yaml.yml",
+			expected: "invalid-yaml.yml: yaml:",
+		},
+		{
+			filename: "../testing/config/invalid-target.yml",
+			expected: `target "server1": field protocol failed on ` +
+				`the "oneof" rule`,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		_, err := LoadConfiguration(test.filename)
+		assert.ErrorContains(t, err, test.expected, test.filename)
+	}
+}
+
+// TestValidateConfiguration tests the ValidateConfiguration function.
+func TestValidateConfiguration(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		name     string
+		targets  []APIConfiguration
+		expected string
+	}{
+		{
+			name:     "No targets",
+			targets:  []APIConfiguration{},
+			expected: "no targets configured",
+		},
+		{
+			name:     "Missing name",
+			targets:  []APIConfiguration{config},
+			expected: "target #1: missing name",
+		},
+		{
+			name: "Duplicated name",
+			targets: []APIConfiguration{
+				{Name: "server1", Hostname: "localhost", Protocol: "http",
+					Port: "2222", Username: "admin", Token: "SECRET"},
+				{Name: "server1", Hostname: "localhost", Protocol: "http",
+					Port: "2222", Username: "admin", Token: "SECRET"},
+			},
+			expected: `target "server1": duplicated name`,
+		},
+		{
+			name: "Invalid label name",
+			targets: []APIConfiguration{
+				{Name: "server1", Hostname: "localhost", Protocol: "http",
+					Port: "2222", Username: "admin", Token: "SECRET",
+					Labels: map[string]string{"data-center": "waw1"}},
+			},
+			expected: `target "server1": invalid label name "data-center"`,
+		},
+		{
+			name: "Invalid nested field",
+			targets: []APIConfiguration{
+				{Name: "server1", Hostname: "localhost", Protocol: "http",
+					Port: "2222", Username: "admin", Token: "SECRET",
+					TLS: TLSConfiguration{KeyFile: "client-key.pem"}},
+			},
+			expected: `target "server1": field tls.cert_file failed on ` +
+				`the "required_with" rule`,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		err := ValidateConfiguration(Configuration{Targets: test.targets})
+		assert.ErrorContains(t, err, test.expected, test.name)
+	}
+}
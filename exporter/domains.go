@@ -0,0 +1,263 @@
+package exporter
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultDomainInterval is the default interval between the refreshes of
+// the domain metrics.
+const defaultDomainInterval = time.Hour
+
+// domainLabels lists the labels of the metrics of the domain collector.
+var domainLabels = []string{"domain", "user"}
+
+// Positions of the fields of the colon-separated domain entries of
+// the CMD_API_SHOW_USER_DOMAINS response.
+const (
+	domainFieldBandwidth  = 0
+	domainFieldQuota      = 2
+	domainFieldSubdomains = 4
+)
+
+// DomainCollectorConfiguration represents the settings of the domain
+// collector. It is disabled by default, as it walks the domains of every
+// user selected by the user filters.
+type DomainCollectorConfiguration struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Interval is the time between the refreshes of the domain metrics,
+	// they are exported from the last refresh in the meantime.
+	Interval time.Duration `yaml:"interval" validate:"gte=0"`
+
+	// Timeout is the timeout of a refresh, separate from the timeout of
+	// the updates, as the domains are walked in the background.
+	Timeout time.Duration `yaml:"timeout" validate:"gte=0"`
+}
+
+// DomainStats represents the usage of a domain.
+type DomainStats struct {
+	Name string
+	User string
+
+	// Bandwidth and Quota are reported in megabytes.
+	Bandwidth  float64
+	Quota      float64
+	Subdomains float64
+	Pointers   float64
+}
+
+// parseDomains returns the domains of the user read from the parsed
+// response of the CMD_API_SHOW_USER_DOMAINS command, sorted by name.
+// Entries which are not in the colon-separated format are skipped.
+func parseDomains(user string, response map[string]interface{}) []DomainStats {
+	domains := []DomainStats{}
+	for name, value := range response {
+		entry, _ := value.(string)
+		if domain, ok := parseDomain(entry); ok {
+			domain.Name = name
+			domain.User = user
+			domains = append(domains, domain)
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].Name < domains[j].Name
+	})
+	return domains
+}
+
+// parseDomain returns the usage read from a colon-separated domain entry, in
+// the bandwidth:bandwidth limit:quota:log usage:subdomains:... format. It
+// reports whether the entry could be parsed.
+func parseDomain(entry string) (DomainStats, bool) {
+	fields := strings.Split(entry, ":")
+	if len(fields) <= domainFieldSubdomains {
+		return DomainStats{}, false
+	}
+
+	values := []float64{}
+	for _, position := range []int{domainFieldBandwidth, domainFieldQuota,
+		domainFieldSubdomains} {
+		value, err := strconv.ParseFloat(fields[position], 64)
+		if err != nil {
+			return DomainStats{}, false
+		}
+		values = append(values, value)
+	}
+	return DomainStats{
+		Bandwidth:  values[0],
+		Quota:      values[1],
+		Subdomains: values[2],
+	}, true
+}
+
+// countEntries returns the number of entries of the parsed response, given
+// as a list or as fields. The error field is not counted.
+func countEntries(response map[string]interface{}) int {
+	if list, ok := response["list"].([]interface{}); ok {
+		return len(list)
+	}
+	count := 0
+	for key := range response {
+		if key != "error" {
+			count++
+		}
+	}
+	return count
+}
+
+// domainCollector is the subcollector exporting the usage of the domains of
+// the users selected by the user filters. The domains are walked in
+// the background once per refresh interval, which is separate from
+// the interval of the updates.
+type domainCollector struct {
+	config    APIConfiguration
+	client    *Client
+	walker    *walker
+	mutex     sync.RWMutex
+	domains   []DomainStats
+	refreshed time.Time
+
+	// Metric descriptions
+	bandwidth  *prometheus.Desc
+	quota      *prometheus.Desc
+	subdomains *prometheus.Desc
+	pointers   *prometheus.Desc
+	refresh    *prometheus.Desc
+}
+
+// newDomainCollector returns a new domainCollector requesting the API with
+// the client.
+func newDomainCollector(config APIConfiguration,
+	client *Client) *domainCollector {
+	d := &domainCollector{
+		config: config,
+		client: client,
+		bandwidth: prometheus.NewDesc("directadmin_domain_bandwidth_bytes",
+			"Bandwidth used by the domain in the current tally period "+
+				"(DirectAdmin command: CMD_API_SHOW_USER_DOMAINS).",
+			domainLabels, config.Labels),
+		quota: prometheus.NewDesc("directadmin_domain_quota_bytes",
+			"Disk space used by the domain "+
+				"(DirectAdmin command: CMD_API_SHOW_USER_DOMAINS).",
+			domainLabels, config.Labels),
+		subdomains: prometheus.NewDesc("directadmin_domain_subdomains",
+			"Number of subdomains of the domain "+
+				"(DirectAdmin command: CMD_API_SHOW_USER_DOMAINS).",
+			domainLabels, config.Labels),
+		pointers: prometheus.NewDesc("directadmin_domain_pointers",
+			"Number of pointers of the domain "+
+				"(DirectAdmin command: CMD_API_DOMAIN_POINTER).",
+			domainLabels, config.Labels),
+		refresh: prometheus.NewDesc(
+			"directadmin_domain_refresh_timestamp_seconds",
+			"Unix time of the last refresh of the domain metrics.",
+			nil, config.Labels),
+	}
+
+	interval := config.Domains.Interval
+	if interval == 0 {
+		interval = defaultDomainInterval
+	}
+	d.walker = newWalker(interval, config.Domains.Timeout, d.walk)
+	return d
+}
+
+// update starts the background walks of the domains with the first call, and
+// returns the error of the last walk once. It doesn't request the API, so
+// the metrics are exported from the last walk.
+func (d *domainCollector) update(context.Context) error {
+	return d.walker.update()
+}
+
+// walk walks the domains of the users. Users whose requests fail are skipped,
+// and the last error is returned. A failed list of the users and a walk
+// interrupted by the end of the context keep the previous snapshot, so that
+// a partial walk isn't exported for the whole refresh interval.
+func (d *domainCollector) walk(ctx context.Context) error {
+	// List users
+	list, err := d.client.Users(ctx)
+	if err != nil {
+		return err
+	}
+	users, _ := filterUsers(userNames(list), d.config.Users)
+
+	// Walk domains of every user
+	domains := []DomainStats{}
+	var lastErr error
+	for _, user := range users {
+		userDomains, err := d.fetch(ctx, user)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		domains = append(domains, userDomains...)
+	}
+
+	// Keep the previous snapshot if the walk was interrupted
+	if err := ctx.Err(); err != nil {
+		return newScrapeError(ReasonNetwork, err)
+	}
+
+	d.store(domains, timeNow())
+	return lastErr
+}
+
+// fetch retrieves the domains of the user and counts their pointers.
+func (d *domainCollector) fetch(ctx context.Context,
+	user string) ([]DomainStats, error) {
+	response, err := d.client.UserDomains(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	domains := parseDomains(user, response)
+	for i := range domains {
+		pointers, err := d.client.DomainPointers(ctx, domains[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		domains[i].Pointers = float64(countEntries(pointers))
+	}
+	return domains, nil
+}
+
+// store replaces the snapshot of the collector.
+func (d *domainCollector) store(domains []DomainStats, refreshed time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.domains = domains
+	d.refreshed = refreshed
+}
+
+// collect sends the metrics of the domains.
+func (d *domainCollector) collect(ch chan<- prometheus.Metric) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for _, domain := range d.domains {
+		ch <- prometheus.MustNewConstMetric(d.bandwidth,
+			prometheus.GaugeValue, domain.Bandwidth*megabyte, domain.Name,
+			domain.User)
+		ch <- prometheus.MustNewConstMetric(d.quota, prometheus.GaugeValue,
+			domain.Quota*megabyte, domain.Name, domain.User)
+		ch <- prometheus.MustNewConstMetric(d.subdomains,
+			prometheus.GaugeValue, domain.Subdomains, domain.Name,
+			domain.User)
+		ch <- prometheus.MustNewConstMetric(d.pointers,
+			prometheus.GaugeValue, domain.Pointers, domain.Name, domain.User)
+	}
+
+	var refreshed float64
+	if !d.refreshed.IsZero() {
+		refreshed = float64(d.refreshed.UnixNano()) / float64(time.Second)
+	}
+	ch <- prometheus.MustNewConstMetric(d.refresh, prometheus.GaugeValue,
+		refreshed)
+}
@@ -0,0 +1,291 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// domainsURL is the URL of the legacy API command listing the domains of
+// the user alice.
+const domainsURL = "http://localhost:2222/CMD_API_SHOW_USER_DOMAINS" +
+	"?user=alice&json=yes"
+
+// pointersURL returns the URL of the legacy API command listing the pointers
+// of the domain.
+func pointersURL(domain string) string {
+	return "http://localhost:2222/CMD_API_DOMAIN_POINTER?domain=" + domain +
+		"&json=yes"
+}
+
+// registerDomains registers the responses of the legacy API listing the user
+// alice, her domains example.com and example.net, and their pointers.
+func registerDomains() {
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/successful.json")))
+	httpmock.RegisterResponder("GET",
+		"http://localhost:2222/CMD_API_SHOW_ALL_USERS?json=yes",
+		httpmock.NewStringResponder(200, `["alice"]`))
+	httpmock.RegisterResponder("GET", domainsURL,
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/user-domains.json")))
+	httpmock.RegisterResponder("GET", pointersURL("example.com"),
+		httpmock.NewStringResponder(200,
+			`{"example.org": "alias", "example.info": "pointer"}`))
+	httpmock.RegisterResponder("GET", pointersURL("example.net"),
+		httpmock.NewStringResponder(200, `[]`))
+}
+
+// TestParseDomains is a unit test for the parseDomains function.
+//
+// It parses a response with valid and invalid domain entries. The function
+// verifies that the valid entries are read and sorted by name.
+func TestParseDomains(t *testing.T) {
+	// Parse response
+	var response map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(responseFromFile(
+		"../testing/api/user-domains.json")), &response))
+	response["error"] = "0"
+	response["invalid.com"] = "1:unlimited:2:0:many:no"
+	response["short.com"] = "1:unlimited:2:0"
+	response["object.com"] = map[string]interface{}{}
+
+	// Test
+	assert.Equal(t, []DomainStats{
+		{
+			Name:       "example.com",
+			User:       "alice",
+			Bandwidth:  1024.5,
+			Quota:      512,
+			Subdomains: 3,
+		},
+		{
+			Name:  "example.net",
+			User:  "alice",
+			Quota: 0.25,
+		},
+	}, parseDomains("alice", response))
+}
+
+// TestCountEntries is a unit test for the countEntries function.
+func TestCountEntries(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		name     string
+		response map[string]interface{}
+		expected int
+	}{
+		{
+			name: "List",
+			response: map[string]interface{}{
+				"list": []interface{}{"example.org"},
+			},
+			expected: 1,
+		},
+		{
+			name: "Fields",
+			response: map[string]interface{}{
+				"example.org":  "alias",
+				"example.info": "pointer",
+				"error":        "0",
+			},
+			expected: 2,
+		},
+		{
+			name:     "Empty",
+			response: map[string]interface{}{},
+			expected: 0,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		assert.Equal(t, test.expected, countEntries(test.response),
+			test.name)
+	}
+}
+
+// domainMetrics is the exposition of the domain metrics of the responses
+// registered by registerDomains, walked at 1688682917.
+const domainMetrics = `
+# HELP directadmin_domain_bandwidth_bytes Bandwidth used by the domain in the current tally period (DirectAdmin command: CMD_API_SHOW_USER_DOMAINS).
+# TYPE directadmin_domain_bandwidth_bytes gauge
+directadmin_domain_bandwidth_bytes{domain="example.com",user="alice"} 1.074266112e+09
+directadmin_domain_bandwidth_bytes{domain="example.net",user="alice"} 0
+# HELP directadmin_domain_pointers Number of pointers of the domain (DirectAdmin command: CMD_API_DOMAIN_POINTER).
+# TYPE directadmin_domain_pointers gauge
+directadmin_domain_pointers{domain="example.com",user="alice"} 2
+directadmin_domain_pointers{domain="example.net",user="alice"} 0
+# HELP directadmin_domain_quota_bytes Disk space used by the domain (DirectAdmin command: CMD_API_SHOW_USER_DOMAINS).
+# TYPE directadmin_domain_quota_bytes gauge
+directadmin_domain_quota_bytes{domain="example.com",user="alice"} 5.36870912e+08
+directadmin_domain_quota_bytes{domain="example.net",user="alice"} 262144
+# HELP directadmin_domain_refresh_timestamp_seconds Unix time of the last refresh of the domain metrics.
+# TYPE directadmin_domain_refresh_timestamp_seconds gauge
+directadmin_domain_refresh_timestamp_seconds 1.688682917e+09
+# HELP directadmin_domain_subdomains Number of subdomains of the domain (DirectAdmin command: CMD_API_SHOW_USER_DOMAINS).
+# TYPE directadmin_domain_subdomains gauge
+directadmin_domain_subdomains{domain="example.com",user="alice"} 3
+directadmin_domain_subdomains{domain="example.net",user="alice"} 0
+` // nolint: revive
+
+// walkDomains returns a collector of the configuration, with the domain
+// collector enabled, and its domain collector once the first background walk
+// of the domains is over.
+func walkDomains(t *testing.T,
+	config APIConfiguration) (*AdminStatsCollector, *domainCollector) {
+	config.Domains.Enabled = true
+	collector := NewAdminStatsCollector(config)
+	domains, _ := collector.subcollectors[0].(*domainCollector)
+	assert.Nil(t, collector.Update(context.Background()))
+	assert.Eventually(t, func() bool {
+		domains.mutex.RLock()
+		defer domains.mutex.RUnlock()
+		return !domains.refreshed.IsZero()
+	}, time.Second, time.Millisecond)
+	return collector, domains
+}
+
+// TestDomainCollector is a unit test for the domain collector of
+// the AdminStatsCollector.
+//
+// It registers the responses of a user with two domains and updates
+// the collector twice. The function verifies the metrics of the domains and
+// that the updates don't wait for the background walk, nor request
+// the domains themselves.
+func TestDomainCollector(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerDomains()
+
+	// Mock the current time
+	timeNow = func() time.Time {
+		return time.Unix(1688682917, 0)
+	}
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	// Update metrics
+	collector, _ := walkDomains(t, config)
+	assert.Equal(t, 5, httpmock.GetTotalCallCount())
+
+	// Test
+	err := testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(domainMetrics),
+		"directadmin_domain_bandwidth_bytes", "directadmin_domain_pointers",
+		"directadmin_domain_quota_bytes",
+		"directadmin_domain_refresh_timestamp_seconds",
+		"directadmin_domain_subdomains")
+	assert.Nil(t, err)
+
+	// Update again
+	assert.Nil(t, collector.Update(context.Background()))
+	assert.Equal(t, 6, httpmock.GetTotalCallCount())
+}
+
+// TestDomainCollectorErrors is a unit test for the failed requests of
+// the domain collector.
+//
+// It walks the domains, then registers failed responses of the domain
+// commands and walks them again. The function verifies that the errors are
+// counted once, that failed users are skipped and that a failed list of
+// the users keeps the previous snapshot.
+func TestDomainCollectorErrors(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		name     string
+		url      string
+		exported int
+	}{
+		{
+			name:     "Failed user list",
+			url:      "http://localhost:2222/CMD_API_SHOW_ALL_USERS?json=yes",
+			exported: 2,
+		},
+		{
+			name: "Failed user domains",
+			url:  domainsURL,
+		},
+		{
+			name: "Failed domain pointers",
+			url:  pointersURL("example.net"),
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		httpmock.Activate()
+		registerDomains()
+		collector, domains := walkDomains(t, config)
+		httpmock.RegisterResponder("GET", test.url,
+			httpmock.NewStringResponder(500, ""))
+
+		domains.walker.walkOnce()
+		err := collector.Update(context.Background())
+		assert.ErrorIs(t, err, ErrHTTPStatus, test.name)
+		assert.Equal(t, 1.0, collector.errors[ReasonHTTP], test.name)
+		assert.Equal(t, 1.0, collector.up, test.name)
+		count, err := testutil.GatherAndCount(collector.Registry(),
+			"directadmin_domain_bandwidth_bytes")
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.exported, count, test.name)
+
+		// Update again
+		assert.Nil(t, collector.Update(context.Background()), test.name)
+
+		httpmock.DeactivateAndReset()
+	}
+}
+
+// TestDomainCollectorTimeout is a unit test for a walk of the domain
+// collector running out of time.
+//
+// It walks the domains, then blocks the requests of the domains until
+// the timeout of the next walk. The function verifies that the error is
+// counted and that the previous snapshot is kept.
+func TestDomainCollectorTimeout(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerDomains()
+
+	// Mock the current time
+	timeNow = func() time.Time {
+		return time.Unix(1688682917, 0)
+	}
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	// Walk domains
+	domains := config
+	domains.Domains.Timeout = 100 * time.Millisecond
+	collector, subcollector := walkDomains(t, domains)
+
+	// Block the next walk until its timeout
+	httpmock.RegisterResponder("GET", domainsURL,
+		func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		})
+	subcollector.walker.walkOnce()
+	err := collector.Update(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1.0, collector.errors[ReasonNetwork])
+
+	// Test
+	err = testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(domainMetrics), "directadmin_domain_pointers",
+		"directadmin_domain_refresh_timestamp_seconds")
+	assert.Nil(t, err)
+}
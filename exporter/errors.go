@@ -0,0 +1,132 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Reasons of the scrape errors reported by
+// the directadmin_scrape_errors_total metric.
+const (
+	ReasonNetwork = "network"
+	ReasonHTTP    = "http"
+	ReasonAuth    = "auth"
+	ReasonParse   = "parse"
+	ReasonAPI     = "api"
+)
+
+// scrapeReasons lists all the scrape error reasons.
+var scrapeReasons = []string{ReasonNetwork, ReasonHTTP, ReasonAuth,
+	ReasonParse, ReasonAPI}
+
+// Errors of the responses of the DirectAdmin API, wrapped in
+// a ResponseError.
+var (
+	// ErrUnauthorized is returned when the API rejects the credentials
+	// with the 401 or 403 status, or with the "Not logged in" API error.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrHTTPStatus is returned for the other statuses of 400 and above.
+	ErrHTTPStatus = errors.New("unexpected HTTP status")
+
+	// ErrNotJSON is returned when the response body is not JSON, such as
+	// the HTML login page of the panel.
+	ErrNotJSON = errors.New("response is not JSON")
+
+	// ErrNotURLEncoded is returned when the response body of the legacy
+	// format cannot be parsed.
+	ErrNotURLEncoded = errors.New("response is not URL-encoded")
+
+	// ErrAPIError is returned when the response has another error field.
+	ErrAPIError = errors.New("API error")
+)
+
+// ErrCircuitOpen is returned when the request is skipped, because
+// the circuit of the target is open. It is not counted as a scrape error.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// responseReasons maps the response errors to their scrape error reasons.
+var responseReasons = map[error]string{
+	ErrUnauthorized:  ReasonAuth,
+	ErrHTTPStatus:    ReasonHTTP,
+	ErrNotJSON:       ReasonParse,
+	ErrNotURLEncoded: ReasonParse,
+	ErrAPIError:      ReasonAPI,
+}
+
+// snippetLength is the maximum length of the body snippet of
+// a ResponseError.
+const snippetLength = 128
+
+// scrapeError represents an error which occurred while retrieving metrics
+// from the DirectAdmin API.
+type scrapeError struct {
+	reason string
+	err    error
+}
+
+// newScrapeError returns a new scrapeError with the provided reason.
+func newScrapeError(reason string, err error) error {
+	return &scrapeError{reason: reason, err: err}
+}
+
+// Error implements the error interface.
+func (e *scrapeError) Error() string {
+	return e.reason + " error: " + e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *scrapeError) Unwrap() error {
+	return e.err
+}
+
+// ErrorReason returns the reason of the scrape error, or an empty string if
+// the error is not a scrape error.
+func ErrorReason(err error) string {
+	var se *scrapeError
+	if errors.As(err, &se) {
+		return se.reason
+	}
+	return ""
+}
+
+// ResponseError represents an invalid response of the DirectAdmin API. It
+// wraps one of ErrUnauthorized, ErrHTTPStatus, ErrNotJSON, ErrNotURLEncoded
+// and ErrAPIError.
+type ResponseError struct {
+	Err        error
+	StatusCode int
+
+	// Snippet holds the beginning of the response body.
+	Snippet string
+}
+
+// newResponseError returns a scrape error wrapping a ResponseError for
+// the response.
+func newResponseError(err error, response Response) error {
+	return newScrapeError(responseReasons[err], &ResponseError{
+		Err:        err,
+		StatusCode: response.StatusCode,
+		Snippet:    snippet(response.Body),
+	})
+}
+
+// Error implements the error interface.
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s (status %d): %q", e.Err, e.StatusCode, e.Snippet)
+}
+
+// Unwrap returns the underlying error.
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
+// snippet returns the beginning of the response body, truncated to
+// snippetLength bytes.
+func snippet(body []byte) string {
+	if len(body) > snippetLength {
+		body = body[:snippetLength]
+	}
+	return strings.ToValidUTF8(strings.TrimSpace(string(body)), "")
+}
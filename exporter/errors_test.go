@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestScrapeError tests the scrapeError type and the ErrorReason function.
+func TestScrapeError(t *testing.T) {
+	// Create errors
+	cause := errors.New("connection refused")
+	err := newScrapeError(ReasonNetwork, cause)
+
+	// Test
+	assert.Equal(t, "network error: connection refused", err.Error())
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, ReasonNetwork, ErrorReason(err))
+	assert.Equal(t, "", ErrorReason(cause))
+}
+
+// TestResponseError tests the ResponseError type.
+func TestResponseError(t *testing.T) {
+	// Create error
+	err := newResponseError(ErrNotJSON, Response{
+		StatusCode: 200,
+		Body:       []byte("  <html><body>Login</body></html>\n"),
+	})
+
+	// Test
+	var responseError *ResponseError
+	assert.ErrorAs(t, err, &responseError)
+	assert.ErrorIs(t, err, ErrNotJSON)
+	assert.Equal(t, 200, responseError.StatusCode)
+	assert.Equal(t, "<html><body>Login</body></html>", responseError.Snippet)
+	assert.Equal(t, ReasonParse, ErrorReason(err))
+	assert.Equal(t, "parse error: response is not JSON (status 200): "+
+		"\"<html><body>Login</body></html>\"", err.Error())
+}
+
+// TestSnippet tests that the snippet function truncates long bodies.
+func TestSnippet(t *testing.T) {
+	// Define body
+	body := []byte(strings.Repeat("a", snippetLength-1) + "\u00e9")
+
+	// Test
+	assert.Equal(t, strings.Repeat("a", snippetLength-1), snippet(body))
+	assert.Equal(t, "", snippet(nil))
+}
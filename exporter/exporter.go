@@ -1,56 +1,411 @@
 package exporter
 
 import (
-	"errors"
-	"log"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-var metrics map[string]prometheus.Gauge
+// convertOptions returns the response conversion options of the target.
+func convertOptions(config APIConfiguration) ConvertOptions {
+	return ConvertOptions{IndexArrays: config.IndexArrays}
+}
+
+// convertUnknown converts the fields unknown to the AdminStats schema with
+// the generic flattener, if enabled for the target. It also returns
+// the number of skipped fields.
+func convertUnknown(unknown map[string]interface{},
+	config APIConfiguration) (map[string]float64, int) {
+	if !config.FlattenUnknownFields {
+		return map[string]float64{}, len(unknown)
+	}
+	return ConvertResponse(unknown, convertOptions(config))
+}
+
+// timeNow returns the current time, it is replaced in tests.
+var timeNow = time.Now
 
-// GetMetrics retrieves the metrics from the DirectAdmin API based on the
-// provided configuration.
-func GetMetrics(config APIConfiguration) (map[string]float64, error) {
-	// Perform API Request
-	response, _ := APIRequest(config)
+// subcollector is an opt-in collector of a target, such as the per-user
+// collector. It is updated after a successful request of the server
+// statistics, and its metrics are sent with them.
+type subcollector interface {
+	update(ctx context.Context) error
+	collect(ch chan<- prometheus.Metric)
+}
 
-	// Parse response
-	parsed, _ := ParseResponse(response)
+// AdminStatsCollector is a prometheus.Collector exporting the server
+// statistics returned by the DirectAdmin API. Every collector owns its own
+// registry, so several of them can live in one process.
+type AdminStatsCollector struct {
+	config   APIConfiguration
+	client   *Client
+	breaker  *circuitBreaker
+	registry *prometheus.Registry
+	mutex    sync.RWMutex
+	stats    AdminStats
+	fallback map[string]float64
 
-	// Handle API errors
-	if parsed["error"] != nil {
-		errMsg := parsed["error"].(string)
-		log.Println(errors.New(errMsg))
-		return map[string]float64{}, errors.New(errMsg)
+	// Opt-in collectors of the target
+	subcollectors []subcollector
+
+	// Scrape health
+	up             float64
+	duration       float64
+	lastSuccessful float64
+	errors         map[string]float64
+	skipped        float64
+}
+
+// NewAdminStatsCollector returns a new AdminStatsCollector for the provided
+// configuration, registered on a fresh registry.
+func NewAdminStatsCollector(config APIConfiguration) *AdminStatsCollector {
+	collector := &AdminStatsCollector{
+		config:   config,
+		client:   NewClient(config),
+		breaker:  &circuitBreaker{config: config.CircuitBreaker},
+		registry: prometheus.NewRegistry(),
+		errors:   map[string]float64{},
 	}
+	for _, reason := range scrapeReasons {
+		collector.errors[reason] = 0
+	}
+	collector.subcollectors = newSubcollectors(config, collector.client)
+	collector.registry.MustRegister(collector)
+	return collector
+}
 
-	// Convert to map[string]float64
-	return ConvertResponse(parsed), nil
+// newSubcollectors returns the opt-in collectors enabled for the target.
+func newSubcollectors(config APIConfiguration,
+	client *Client) []subcollector {
+	subcollectors := []subcollector{}
+	if config.Users.Enabled {
+		subcollectors = append(subcollectors, newUserCollector(config, client))
+	}
+	if config.Resellers.Enabled {
+		subcollectors = append(subcollectors,
+			newResellerCollector(config, client))
+	}
+	if config.Domains.Enabled {
+		subcollectors = append(subcollectors,
+			newDomainCollector(config, client))
+	}
+	if config.Services.Enabled {
+		subcollectors = append(subcollectors,
+			newServiceCollector(config, client))
+	}
+	if config.License.Enabled {
+		subcollectors = append(subcollectors,
+			newLicenseCollector(config, client))
+	}
+	return subcollectors
 }
 
-// RecordMetrics retrieves and records the metrics from the DirectAdmin API
-// based on the provided configuration.
-func RecordMetrics(config APIConfiguration) map[string]prometheus.Gauge {
-	if metrics == nil {
-		metrics = make(map[string]prometheus.Gauge)
+// Registry returns the registry the collector is registered on.
+func (c *AdminStatsCollector) Registry() *prometheus.Registry {
+	return c.registry
+}
+
+// Update retrieves the metrics from the DirectAdmin API and stores them as
+// the latest snapshot. The snapshot is cleared when the request fails, so
+// stale values are never exported. The request is canceled when the context
+// is done, and skipped with ErrCircuitOpen while the circuit is open. Errors
+// of the opt-in collectors are counted, but don't mark the target as down.
+func (c *AdminStatsCollector) Update(ctx context.Context) error {
+	// Check circuit
+	start := timeNow()
+	c.mutex.RLock()
+	open := c.breaker.isOpen(start)
+	c.mutex.RUnlock()
+	if open {
+		return ErrCircuitOpen
 	}
-	// Get metrics
-	m, err := GetMetrics(config)
+
+	// Get response
+	parsed, err := c.client.AdminStats(ctx)
+	if err := c.store(parsed, err, start); err != nil {
+		return err
+	}
+
+	// Update opt-in collectors
+	return c.updateSubcollectors(ctx)
+}
+
+// store replaces the snapshot with the response of the request started at
+// the provided time, and returns the error of the request.
+func (c *AdminStatsCollector) store(parsed map[string]interface{}, err error,
+	start time.Time) error {
+	end := timeNow()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.breaker.record(end, err)
+	c.stats = NewAdminStats(parsed, c.config)
+	fallback, skipped := convertUnknown(c.stats.Unknown, c.config)
+	c.fallback = fallback
+	c.skipped += float64(skipped)
+	c.duration = end.Sub(start).Seconds()
 	if err != nil {
-		return metrics
+		c.up = 0
+		c.errors[ErrorReason(err)]++
+		return err
+	}
+	c.up = 1
+	c.lastSuccessful = float64(end.UnixNano()) / float64(time.Second)
+
+	return nil
+}
+
+// updateSubcollectors updates the opt-in collectors of the target, and
+// returns the last error.
+func (c *AdminStatsCollector) updateSubcollectors(ctx context.Context) error {
+	var lastErr error
+	for _, subcollector := range c.subcollectors {
+		if err := subcollector.update(ctx); err != nil {
+			c.mutex.Lock()
+			c.errors[ErrorReason(err)]++
+			c.mutex.Unlock()
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// Describe implements prometheus.Collector. The collector is unchecked,
+// because the set of metrics depends on the API response.
+func (*AdminStatsCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector. It builds constant metrics from
+// the latest snapshot.
+func (c *AdminStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	// Record the names of the metrics sent, until the fallback
+	sent := map[string]bool{}
+	metrics := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for metric := range metrics {
+			sent[metricName(metric.Desc())] = true
+			ch <- metric
+		}
+		close(done)
+	}()
+	c.collectSnapshot(metrics)
+	close(metrics)
+	<-done
+
+	// Fields unknown to the AdminStats schema, unless they would duplicate
+	// a metric of the exporter
+	for key, value := range c.fallback {
+		if !sent["directadmin_"+key] {
+			c.collectGauge(ch, key, fmt.Sprintf("Value of the DirectAdmin "+
+				"field %s, exported by the generic flattener.", key), value)
+		}
 	}
+}
+
+// collectSnapshot sends the metrics of the latest snapshot, except the ones
+// of the generic flattener.
+func (c *AdminStatsCollector) collectSnapshot(ch chan<- prometheus.Metric) {
+	// Scrape health
+	c.collectHealth(ch)
+
+	// API metrics
+	c.collectInfo(ch)
+	c.collectUsage(ch)
+	c.collectResources(ch)
+	c.collectFilesystems(ch)
 
-	// Return map of prometheus gauges
-	for key, value := range m {
-		if _, exist := metrics[key]; !exist {
-			metrics[key] = promauto.NewGauge(prometheus.GaugeOpts{
-				Name: "directadmin_" + key,
-			})
+	// Opt-in collectors, sent only after a successful request
+	if c.up == 1 {
+		for _, subcollector := range c.subcollectors {
+			subcollector.collect(ch)
 		}
-		metrics[key].Set(value)
 	}
+}
 
-	return metrics
+// metricName returns the fully-qualified name of the metric description,
+// read from its string representation, as prometheus.Desc has no getter.
+func metricName(desc *prometheus.Desc) string {
+	_, name, _ := strings.Cut(desc.String(), `fqName: "`)
+	name, _, _ = strings.Cut(name, `"`)
+	return name
+}
+
+// collectGauge sends a single gauge of the collector.
+func (c *AdminStatsCollector) collectGauge(ch chan<- prometheus.Metric,
+	name string, help string, value float64) {
+	c.collectMetric(ch, metricDefinition{name: name, help: help,
+		valueType: prometheus.GaugeValue}, value)
+}
+
+// collectMetric sends a single metric of the catalog.
+func (c *AdminStatsCollector) collectMetric(ch chan<- prometheus.Metric,
+	metric metricDefinition, value float64) {
+	desc := prometheus.NewDesc("directadmin_"+metric.name, metric.help, nil,
+		c.config.Labels)
+	ch <- prometheus.MustNewConstMetric(desc, metric.valueType,
+		metric.convert(value))
+}
+
+// collectUsage sends the usage and load average metrics of the collector.
+func (c *AdminStatsCollector) collectUsage(ch chan<- prometheus.Metric) {
+	for _, metric := range serverMetrics {
+		if value, exists := c.stats.Usage[metric.field]; exists {
+			c.collectMetric(ch, metric, value)
+		}
+	}
+
+	if lastTally, exists := c.stats.Usage["last_tally"]; exists {
+		c.collectTally(ch, lastTally)
+	}
+
+	if loadavg := c.stats.LoadAverage; loadavg != nil {
+		c.collectGauge(ch, "loadavg_one", "System load average over "+
+			"1 minute (DirectAdmin field: loadavg.one).", loadavg.One)
+		c.collectGauge(ch, "loadavg_five", "System load average over "+
+			"5 minutes (DirectAdmin field: loadavg.five).", loadavg.Five)
+		c.collectGauge(ch, "loadavg_fifteen", "System load average over "+
+			"15 minutes (DirectAdmin field: loadavg.fifteen).",
+			loadavg.Fifteen)
+	}
+}
+
+// collectInfo sends the server info metric, carrying the hostname of
+// the target and the string fields of the API response as labels. It is
+// sent only after a successful request.
+func (c *AdminStatsCollector) collectInfo(ch chan<- prometheus.Metric) {
+	if c.up == 0 {
+		return
+	}
+
+	labels := []string{"hostname"}
+	values := []string{c.config.Hostname}
+	for _, field := range infoFields(c.config) {
+		labels = append(labels, field)
+		values = append(values, c.stats.Attributes[field])
+	}
+
+	desc := prometheus.NewDesc("directadmin_server_info",
+		"Server attributes reported by DirectAdmin, the value is always 1.",
+		labels, c.config.Labels)
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1,
+		values...)
+}
+
+// collectTally sends the metrics derived from the time of the last tally.
+// The tally age is computed at scrape time, if enabled for the target.
+func (c *AdminStatsCollector) collectTally(ch chan<- prometheus.Metric,
+	lastTally float64) {
+	c.collectGauge(ch, "tally_period_start_timestamp_seconds",
+		"Unix time of the start of the monthly tally period.",
+		float64(tallyPeriodStart(lastTally).Unix()))
+
+	if c.config.TallyAge {
+		age := float64(timeNow().UnixNano())/float64(time.Second) -
+			lastTally
+		c.collectGauge(ch, "tally_age_seconds",
+			"Number of seconds since the last tally.", age)
+	}
+}
+
+// collectHealth sends the scrape health metrics of the collector.
+func (c *AdminStatsCollector) collectHealth(ch chan<- prometheus.Metric) {
+	labels := c.config.Labels
+	ch <- prometheus.MustNewConstMetric(prometheus.NewDesc("directadmin_up",
+		"Whether the last request to the DirectAdmin API was successful.",
+		nil, labels), prometheus.GaugeValue, c.up)
+	ch <- prometheus.MustNewConstMetric(prometheus.NewDesc(
+		"directadmin_scrape_duration_seconds",
+		"Duration of the last request to the DirectAdmin API.",
+		nil, labels), prometheus.GaugeValue, c.duration)
+	ch <- prometheus.MustNewConstMetric(prometheus.NewDesc(
+		"directadmin_last_successful_scrape_timestamp_seconds",
+		"Unix time of the last successful request to the DirectAdmin API.",
+		nil, labels), prometheus.GaugeValue, c.lastSuccessful)
+
+	ch <- prometheus.MustNewConstMetric(prometheus.NewDesc(
+		"directadmin_target_circuit_open",
+		"Whether the requests to the target are stopped by the circuit "+
+			"breaker.", nil, labels), prometheus.GaugeValue,
+		boolToFloat(c.breaker.isOpen(timeNow())))
+
+	ch <- prometheus.MustNewConstMetric(prometheus.NewDesc(
+		"directadmin_parse_skipped_fields_total",
+		"Number of fields of the API responses skipped by the parser.",
+		nil, labels), prometheus.CounterValue, c.skipped)
+
+	desc := prometheus.NewDesc("directadmin_scrape_errors_total",
+		"Number of failed requests to the DirectAdmin API by reason.",
+		[]string{"reason"}, labels)
+	for reason, value := range c.errors {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue,
+			value, reason)
+	}
+}
+
+// collectResources sends the usage and allocation metrics of
+// the resources.
+func (c *AdminStatsCollector) collectResources(ch chan<- prometheus.Metric) {
+	resource := prometheus.NewDesc("directadmin_resource",
+		"Usage or allocated amount of the resource, in bytes for quota "+
+			"and bandwidth, +Inf if the allocation is unlimited "+
+			"(DirectAdmin fields: usage.<resource>, allocated.<resource>).",
+		[]string{"resource", "kind"}, c.config.Labels)
+	unlimited := prometheus.NewDesc("directadmin_allocated_unlimited",
+		"Whether the allocation of the resource is unlimited "+
+			"(DirectAdmin field: allocated.<resource>).",
+		[]string{"resource"}, c.config.Labels)
+
+	for _, field := range resourceFields {
+		if value, exists := c.stats.Usage[field]; exists {
+			ch <- prometheus.MustNewConstMetric(resource,
+				prometheus.GaugeValue, value*resourceScale(field), field,
+				"usage")
+		}
+	}
+	for _, allocation := range c.stats.Allocations {
+		ch <- prometheus.MustNewConstMetric(resource,
+			prometheus.GaugeValue,
+			allocation.Value*resourceScale(allocation.Resource),
+			allocation.Resource, "allocated")
+		ch <- prometheus.MustNewConstMetric(unlimited,
+			prometheus.GaugeValue, boolToFloat(allocation.Unlimited),
+			allocation.Resource)
+	}
+}
+
+// boolToFloat converts a boolean to a metric value.
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// collectFilesystems sends the filesystem metrics of the collector.
+func (c *AdminStatsCollector) collectFilesystems(ch chan<- prometheus.Metric) {
+	labels := []string{"device", "mountpoint"}
+	size := prometheus.NewDesc("directadmin_filesystem_size_bytes",
+		"Filesystem size in bytes (DirectAdmin fields: diskN).",
+		labels, c.config.Labels)
+	used := prometheus.NewDesc("directadmin_filesystem_used_bytes",
+		"Filesystem used space in bytes (DirectAdmin fields: diskN).",
+		labels, c.config.Labels)
+	avail := prometheus.NewDesc("directadmin_filesystem_avail_bytes",
+		"Filesystem space available in bytes (DirectAdmin fields: diskN).",
+		labels, c.config.Labels)
+
+	for _, fs := range c.stats.Filesystems {
+		ch <- prometheus.MustNewConstMetric(size, prometheus.GaugeValue,
+			fs.Size, fs.Device, fs.Mountpoint)
+		ch <- prometheus.MustNewConstMetric(used, prometheus.GaugeValue,
+			fs.Used, fs.Device, fs.Mountpoint)
+		ch <- prometheus.MustNewConstMetric(avail, prometheus.GaugeValue,
+			fs.Avail, fs.Device, fs.Mountpoint)
+	}
 }
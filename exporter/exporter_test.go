@@ -1,21 +1,28 @@
 package exporter
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
-// TestGetMetrics is a unit test for the GetMetrics function.
+// TestClientAdminStatsMetrics is a unit test for the metrics converted from
+// the response of the Client.AdminStats method.
 //
 // It activates the HTTP mock, configures the response, registers
-// the response function, gets the metrics using the GetMetrics function.
-// The function verifies that the metrics fetched from the API match
-// the expected values.
-func TestGetMetrics(t *testing.T) {
+// the response function, gets the statistics using the Client.AdminStats
+// method and converts them with the generic flattener. The function
+// verifies that the metrics fetched from the API match the expected values.
+func TestClientAdminStatsMetrics(t *testing.T) {
 	// Activate HTTP mock
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
@@ -27,12 +34,12 @@ func TestGetMetrics(t *testing.T) {
 	}
 
 	// Register response
-	httpmock.RegisterResponder("GET", fmt.Sprintf(urlFormat,
-		config.Protocol, config.Username, config.Token, config.Hostname,
-		config.Port), responseFunction(test))
+	httpmock.RegisterResponder("GET", statsURL(config), responseFunction(test))
 
 	// Get metrics
-	metrics, _ := GetMetrics(config)
+	parsed, err := NewClient(config).AdminStats(context.Background())
+	assert.Nil(t, err)
+	metrics, _ := ConvertResponse(parsed, convertOptions(config))
 
 	// Define tests
 	tests := []struct {
@@ -52,14 +59,14 @@ func TestGetMetrics(t *testing.T) {
 	}
 }
 
-// TestGetMetricsAPIError is a unit test for the GetMetrics function when
-// the API returns an error.
+// TestClientAdminStatsAPIError is a unit test for the Client.AdminStats
+// method when the API returns an error.
 //
 // It activates the HTTP mock, configures the response, registers
-// the response function, gets the metrics using the GetMetrics function.
-// The function verifies that the metrics fetched from the API match
-// the expected values.
-func TestGetMetricsAPIError(t *testing.T) {
+// the response function, gets the statistics using the Client.AdminStats
+// method. The function verifies that the error is returned with an empty
+// response.
+func TestClientAdminStatsAPIError(t *testing.T) {
 	// Activate HTTP mock
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
@@ -71,33 +78,39 @@ func TestGetMetricsAPIError(t *testing.T) {
 	}
 
 	// Register response
-	httpmock.RegisterResponder("GET", fmt.Sprintf(urlFormat,
-		config.Protocol, config.Username, config.Token, config.Hostname,
-		config.Port), responseFunction(test))
+	httpmock.RegisterResponder("GET", statsURL(config), responseFunction(test))
 
-	// Get metrics
-	metrics, err := GetMetrics(config)
+	// Get statistics
+	parsed, err := NewClient(config).AdminStats(context.Background())
 
-	// Metrics should return error
+	// Statistics should return error
 	assert.Error(t, err)
-	assert.Equal(t, map[string]float64{}, metrics)
+	assert.Equal(t, map[string]interface{}{}, parsed)
+}
+
+// gatheredNames returns the names of the metric families gathered from
+// the provided registry.
+func gatheredNames(t *testing.T, registry *prometheus.Registry) []string {
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+
+	names := []string{}
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	return names
 }
 
-// TestRecordMetrics is a unit test for the RecordMetrics function.
+// TestAdminStatsCollectorUpdate is a unit test for the AdminStatsCollector
+// Update and Collect methods.
 //
 // It activates the HTTP mock, configures the response, registers
-// the response function, gets the metrics using the GetMetrics function.
-// The function verifies that the metrics fetched from the API match
-// the expected values.
-func TestRecordMetrics(t *testing.T) {
-	// Clean environment after test
-	defer func() {
-		httpmock.DeactivateAndReset()
-		metrics = make(map[string]prometheus.Gauge)
-	}()
-
+// the response function and updates the collector. The function verifies
+// that the registry of the collector exposes the expected metrics.
+func TestAdminStatsCollectorUpdate(t *testing.T) {
 	// Activate HTTP mock
 	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
 
 	// Configure response
 	var test = APIResponseTest{
@@ -106,46 +119,413 @@ func TestRecordMetrics(t *testing.T) {
 	}
 
 	// Register response
-	httpmock.RegisterResponder("GET", fmt.Sprintf(urlFormat,
-		config.Protocol, config.Username, config.Token, config.Hostname,
-		config.Port), responseFunction(test))
+	httpmock.RegisterResponder("GET", statsURL(config), responseFunction(test))
 
-	// Get metrics
-	metrics := RecordMetrics(config)
+	// Update metrics
+	collector := NewAdminStatsCollector(config)
+	assert.Nil(t, collector.Update(context.Background()))
 
 	// Define tests
 	tests := []struct {
-		key      string
-		exists   bool
-		expected float64
+		name   string
+		exists bool
 	}{
-		{key: "bandwidth", exists: true},
-		{key: "loadavg_five", exists: true},
-		{key: "disk1", exists: false},
+		{name: "directadmin_bandwidth", exists: false},
+		{name: "directadmin_resource", exists: true},
+		{name: "directadmin_nusers", exists: true},
+		{name: "directadmin_email_deliveries_total", exists: true},
+		{name: "directadmin_email_deliveries", exists: false},
+		{name: "directadmin_loadavg_five", exists: true},
+		{name: "directadmin_disk1", exists: false},
+		{name: "directadmin_allocated_quota", exists: false},
+		{name: "directadmin_usage_bandwidth", exists: false},
+		{name: "directadmin_disk_info_ipp", exists: false},
+		{name: "directadmin_disk_info_columns_filesystem", exists: false},
+		{name: "directadmin_allocated_unlimited", exists: true},
+		{name: "directadmin_filesystem_size_bytes", exists: true},
+		{name: "directadmin_filesystem_used_bytes", exists: true},
+		{name: "directadmin_filesystem_avail_bytes", exists: true},
 	}
 
+	names := gatheredNames(t, collector.Registry())
 	for _, test := range tests {
-		_, exists := metrics[test.key]
-		assert.Equal(t, test.exists, exists)
+		assert.Equal(t, test.exists, slices.Contains(names, test.name))
 	}
 }
 
-// TestRecordMetricsAPIError is a unit test for the RecordMetrics function
-// when the API returns an error.
+// TestAdminStatsCollectorFilesystems is a unit test for the filesystem
+// metrics of the AdminStatsCollector.
 //
-// It activates the HTTP mock, configures the response, registers
-// the response function, gets the metrics using the GetMetrics function,
-// and defines the tests. The function verifies that the metrics fetched
-// from the API match the expected values.
-func TestRecordMetricsAPIError(t *testing.T) {
-	// Clean environment after test
+// It activates the HTTP mock, registers the response and updates
+// collectors with the default and a custom filesystem filter. The function
+// verifies the exported filesystems.
+func TestAdminStatsCollectorFilesystems(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register response
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/successful.json")))
+
+	// Define tests
+	tests := []struct {
+		exclude  string
+		expected int
+	}{
+		{exclude: "", expected: 4},
+		{exclude: "^$", expected: 9},
+		{exclude: "^(tmpfs|devtmpfs|/dev/sda.*)$", expected: 2},
+	}
+
+	// Perform tests
+	for _, test := range tests {
+		target := config
+		target.FilesystemExclude = test.exclude
+		collector := NewAdminStatsCollector(target)
+		assert.Nil(t, collector.Update(context.Background()))
+
+		count, err := testutil.GatherAndCount(collector.Registry(),
+			"directadmin_filesystem_size_bytes")
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, count, test.exclude)
+	}
+
+	// Test values
+	expected := `
+# HELP directadmin_filesystem_avail_bytes Filesystem space available in bytes (DirectAdmin fields: diskN).
+# TYPE directadmin_filesystem_avail_bytes gauge
+directadmin_filesystem_avail_bytes{device="/dev/sda1",mountpoint="/"} 3.3139224576e+10
+directadmin_filesystem_avail_bytes{device="/dev/sda15",mountpoint="/boot/efi"} 6.4735232e+07
+directadmin_filesystem_avail_bytes{device="/dev/sdb",mountpoint="/home"} 3.9810138112e+10
+directadmin_filesystem_avail_bytes{device="/dev/sdc",mountpoint="/volume"} 1.981704192e+10
+` // nolint: revive
+	collector := NewAdminStatsCollector(config)
+	assert.Nil(t, collector.Update(context.Background()))
+	assert.Nil(t, testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_filesystem_avail_bytes"))
+}
+
+// TestAdminStatsCollectorResources is a unit test for the resource
+// metrics of the AdminStatsCollector.
+//
+// It activates the HTTP mock, registers the response and updates
+// the collector. The function verifies that usage, limited and unlimited
+// allocations are exported as labels of a single metric.
+func TestAdminStatsCollectorResources(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register response
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			`{"allocated": {"bandwidth": "unlimited", "quota": "845790", `+
+				`"vdomains": "10"}, "usage": {"bandwidth": "85541", `+
+				`"quota": "10240"}}`))
+
+	// Update metrics
+	collector := NewAdminStatsCollector(config)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Expected metrics
+	expected := `
+# HELP directadmin_allocated_unlimited Whether the allocation of the resource is unlimited (DirectAdmin field: allocated.<resource>).
+# TYPE directadmin_allocated_unlimited gauge
+directadmin_allocated_unlimited{resource="bandwidth"} 1
+directadmin_allocated_unlimited{resource="quota"} 0
+directadmin_allocated_unlimited{resource="vdomains"} 0
+# HELP directadmin_resource Usage or allocated amount of the resource, in bytes for quota and bandwidth, +Inf if the allocation is unlimited (DirectAdmin fields: usage.<resource>, allocated.<resource>).
+# TYPE directadmin_resource gauge
+directadmin_resource{kind="allocated",resource="bandwidth"} +Inf
+directadmin_resource{kind="allocated",resource="quota"} 8.8687509504e+11
+directadmin_resource{kind="allocated",resource="vdomains"} 10
+directadmin_resource{kind="usage",resource="bandwidth"} 8.9696239616e+10
+directadmin_resource{kind="usage",resource="quota"} 1.073741824e+10
+` // nolint: revive
+
+	// Test
+	assert.Nil(t, testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_resource",
+		"directadmin_allocated_unlimited"))
+}
+
+// TestAdminStatsCollectorSkippedFields is a unit test for the skipped
+// fields counter of the AdminStatsCollector.
+//
+// It activates the HTTP mock, registers a response with fields of
+// unexpected types and updates the collector twice. The function verifies
+// that the skipped fields are counted instead of causing a panic.
+func TestAdminStatsCollectorSkippedFields(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register response
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			`{"nusers": 211, "list": [1, 2], "unknown": null}`))
+
+	// Update metrics
+	collector := NewAdminStatsCollector(config)
+	assert.Nil(t, collector.Update(context.Background()))
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Expected metrics
+	expected := `
+# HELP directadmin_nusers Number of users (DirectAdmin field: nusers).
+# TYPE directadmin_nusers gauge
+directadmin_nusers 211
+# HELP directadmin_parse_skipped_fields_total Number of fields of the API responses skipped by the parser.
+# TYPE directadmin_parse_skipped_fields_total counter
+directadmin_parse_skipped_fields_total 4
+` // nolint: revive
+
+	// Test
+	assert.Nil(t, testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_nusers",
+		"directadmin_parse_skipped_fields_total"))
+}
+
+// TestAdminStatsCollectorCounters is a unit test for the counters of
+// the AdminStatsCollector.
+//
+// It activates the HTTP mock, registers the response and updates
+// the collector. The function verifies that the values reset by the monthly
+// tally are exported as counters, together with the tally period start.
+func TestAdminStatsCollectorCounters(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register response
+	lastTally := time.Date(2023, 7, 6, 22, 35, 17, 0, time.UTC)
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200, fmt.Sprintf(
+			`{"usage": {"bandwidth": "85541", `+
+				`"email_deliveries_incoming": "7974", `+
+				`"last_tally": "%d"}}`, lastTally.Unix())))
+
+	// Update metrics
+	collector := NewAdminStatsCollector(config)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Expected metrics
+	expected := fmt.Sprintf(`
+# HELP directadmin_bandwidth_bytes_total Bandwidth used in the current tally period in bytes, reset by the monthly tally (DirectAdmin field: bandwidth).
+# TYPE directadmin_bandwidth_bytes_total counter
+directadmin_bandwidth_bytes_total 8.9696239616e+10
+# HELP directadmin_email_deliveries_incoming_total Incoming email deliveries in the current tally period, reset by the monthly tally (DirectAdmin field: email_deliveries_incoming).
+# TYPE directadmin_email_deliveries_incoming_total counter
+directadmin_email_deliveries_incoming_total 7974
+# HELP directadmin_tally_period_start_timestamp_seconds Unix time of the start of the monthly tally period.
+# TYPE directadmin_tally_period_start_timestamp_seconds gauge
+directadmin_tally_period_start_timestamp_seconds %d
+`, time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC).Unix()) // nolint: revive
+
+	// Test
+	assert.Nil(t, testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_bandwidth_bytes_total",
+		"directadmin_email_deliveries_incoming_total",
+		"directadmin_tally_period_start_timestamp_seconds"))
+}
+
+// TestAdminStatsCollectorTally is a unit test for the tally metrics of
+// the AdminStatsCollector.
+//
+// It activates the HTTP mock, registers the response, mocks the current
+// time and updates collectors with the tally age disabled and enabled.
+// The function verifies the last tally timestamp and the tally age.
+func TestAdminStatsCollectorTally(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register response
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			`{"usage": {"last_tally": "1688682917"}}`))
+
+	// Mock the current time
+	timeNow = func() time.Time {
+		return time.Unix(1688682917+3600, 0)
+	}
 	defer func() {
-		httpmock.DeactivateAndReset()
-		metrics = make(map[string]prometheus.Gauge)
+		timeNow = time.Now
 	}()
 
+	// Expected metrics
+	expected := `
+# HELP directadmin_last_tally_timestamp_seconds Unix time of the last tally (DirectAdmin field: last_tally).
+# TYPE directadmin_last_tally_timestamp_seconds gauge
+directadmin_last_tally_timestamp_seconds 1.688682917e+09
+`
+	expectedAge := expected + `
+# HELP directadmin_tally_age_seconds Number of seconds since the last tally.
+# TYPE directadmin_tally_age_seconds gauge
+directadmin_tally_age_seconds 3600
+` // nolint: revive
+
+	// Define tests
+	tests := []struct {
+		tallyAge bool
+		expected string
+	}{
+		{tallyAge: false, expected: expected},
+		{tallyAge: true, expected: expectedAge},
+	}
+
+	// Perform tests
+	for _, test := range tests {
+		target := config
+		target.TallyAge = test.tallyAge
+		collector := NewAdminStatsCollector(target)
+		assert.Nil(t, collector.Update(context.Background()))
+
+		assert.Nil(t, testutil.GatherAndCompare(collector.Registry(),
+			strings.NewReader(test.expected),
+			"directadmin_last_tally_timestamp_seconds",
+			"directadmin_tally_age_seconds"))
+	}
+}
+
+// TestAdminStatsCollectorInfo is a unit test for the server info metric of
+// the AdminStatsCollector.
+//
+// It activates the HTTP mock, registers the response and updates
+// the collector with a custom set of info fields. The function verifies
+// the labels of the server info metric.
+func TestAdminStatsCollectorInfo(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register response
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			`{"device": "eth0:1", "version": "1.65.1"}`))
+
+	// Update metrics
+	target := config
+	target.InfoFields = []string{"device", "version"}
+	collector := NewAdminStatsCollector(target)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Expected metrics
+	expected := `
+# HELP directadmin_server_info Server attributes reported by DirectAdmin, the value is always 1.
+# TYPE directadmin_server_info gauge
+directadmin_server_info{device="eth0:1",hostname="localhost",version="1.65.1"} 1
+# HELP directadmin_parse_skipped_fields_total Number of fields of the API responses skipped by the parser.
+# TYPE directadmin_parse_skipped_fields_total counter
+directadmin_parse_skipped_fields_total 0
+` // nolint: revive
+
+	// Test
+	assert.Nil(t, testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_server_info",
+		"directadmin_parse_skipped_fields_total"))
+}
+
+// TestAdminStatsCollectorFallback is a unit test for the generic flattener
+// fallback of the AdminStatsCollector.
+//
+// It activates the HTTP mock, registers a response with fields unknown to
+// the AdminStats schema and updates collectors with the fallback disabled
+// and enabled. The function verifies the exported unknown fields.
+func TestAdminStatsCollectorFallback(t *testing.T) {
 	// Activate HTTP mock
 	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register response
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			`{"bandwidth": "85541", "new": {"field": "7"}}`))
+
+	// Define tests
+	tests := []struct {
+		flatten bool
+		exists  bool
+	}{
+		{flatten: false, exists: false},
+		{flatten: true, exists: true},
+	}
+
+	// Perform tests
+	for _, test := range tests {
+		target := config
+		target.FlattenUnknownFields = test.flatten
+		collector := NewAdminStatsCollector(target)
+		assert.Nil(t, collector.Update(context.Background()))
+
+		names := gatheredNames(t, collector.Registry())
+		assert.True(t, slices.Contains(names,
+			"directadmin_bandwidth_bytes_total"))
+		assert.Equal(t, test.exists,
+			slices.Contains(names, "directadmin_new_field"))
+	}
+}
+
+// TestAdminStatsCollectorFallbackDuplicates is a unit test for the unknown
+// fields of the generic flattener named like metrics of the exporter.
+//
+// It activates the HTTP mock, registers a response with the unknown fields
+// up and license.valid and updates a collector with the fallback and
+// the license collector enabled. The function verifies that the scrape
+// succeeds with the metrics of the exporter, and that the other unknown
+// fields are still exported.
+func TestAdminStatsCollectorFallbackDuplicates(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register responses
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200, `{"up": "5", `+
+			`"license": {"valid": "7"}, "new": {"field": "7"}}`))
+	httpmock.RegisterResponder("GET", licenseURL,
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/license.json")))
+
+	// Update metrics
+	target := config
+	target.FlattenUnknownFields = true
+	target.License = LicenseCollectorConfiguration{Enabled: true}
+	collector := NewAdminStatsCollector(target)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Expected metrics
+	expected := `
+# HELP directadmin_new_field Value of the DirectAdmin field new_field, exported by the generic flattener.
+# TYPE directadmin_new_field gauge
+directadmin_new_field 7
+# HELP directadmin_up Whether the last request to the DirectAdmin API was successful.
+# TYPE directadmin_up gauge
+directadmin_up 1
+` // nolint: revive
+
+	// Test
+	assert.Nil(t, testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_new_field",
+		"directadmin_up"))
+	count, err := testutil.GatherAndCount(collector.Registry(),
+		"directadmin_license_valid")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+}
+
+// TestAdminStatsCollectorUpdateAPIError is a unit test for the
+// AdminStatsCollector Update method when the API returns an error.
+//
+// It activates the HTTP mock, configures the response, registers
+// the response function and updates the collector. The function verifies
+// that an error is returned and only the scrape health metrics are exposed.
+func TestAdminStatsCollectorUpdateAPIError(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
 
 	// Configure response
 	var test = APIResponseTest{
@@ -154,13 +534,237 @@ func TestRecordMetricsAPIError(t *testing.T) {
 	}
 
 	// Register response
-	httpmock.RegisterResponder("GET", fmt.Sprintf(urlFormat,
-		config.Protocol, config.Username, config.Token, config.Hostname,
-		config.Port), responseFunction(test))
+	httpmock.RegisterResponder("GET", statsURL(config), responseFunction(test))
 
-	// Get metrics
-	metrics := RecordMetrics(config)
+	// Update metrics
+	collector := NewAdminStatsCollector(config)
+	assert.Error(t, collector.Update(context.Background()))
+
+	// Only scrape health metrics should be exposed, without server info
+	assert.Equal(t, []string{
+		"directadmin_last_successful_scrape_timestamp_seconds",
+		"directadmin_parse_skipped_fields_total",
+		"directadmin_scrape_duration_seconds",
+		"directadmin_scrape_errors_total",
+		"directadmin_target_circuit_open",
+		"directadmin_up",
+	}, gatheredNames(t, collector.Registry()))
+}
+
+// TestAdminStatsCollectorHealth is a unit test for the scrape health
+// metrics of the AdminStatsCollector.
+//
+// It activates the HTTP mock, registers a successful and a failed response
+// and updates the collector with each of them. The function verifies
+// the values of the scrape health metrics.
+func TestAdminStatsCollectorHealth(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Mock the current time
+	now := time.Unix(1688682917, 0)
+	timeNow = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	// Successful request
+	url := statsURL(config)
+	httpmock.RegisterResponder("GET", url, httpmock.NewStringResponder(200,
+		responseFromFile("../testing/api/successful.json")))
+	collector := NewAdminStatsCollector(config)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Failed request
+	httpmock.RegisterResponder("GET", url,
+		httpmock.NewStringResponder(500, ""))
+	assert.Error(t, collector.Update(context.Background()))
+
+	// Expected metrics
+	expected := `
+# HELP directadmin_last_successful_scrape_timestamp_seconds Unix time of the last successful request to the DirectAdmin API.
+# TYPE directadmin_last_successful_scrape_timestamp_seconds gauge
+directadmin_last_successful_scrape_timestamp_seconds 1.688682919e+09
+# HELP directadmin_scrape_duration_seconds Duration of the last request to the DirectAdmin API.
+# TYPE directadmin_scrape_duration_seconds gauge
+directadmin_scrape_duration_seconds 1
+# HELP directadmin_scrape_errors_total Number of failed requests to the DirectAdmin API by reason.
+# TYPE directadmin_scrape_errors_total counter
+directadmin_scrape_errors_total{reason="api"} 0
+directadmin_scrape_errors_total{reason="auth"} 0
+directadmin_scrape_errors_total{reason="http"} 1
+directadmin_scrape_errors_total{reason="network"} 0
+directadmin_scrape_errors_total{reason="parse"} 0
+# HELP directadmin_up Whether the last request to the DirectAdmin API was successful.
+# TYPE directadmin_up gauge
+directadmin_up 0
+` // nolint: revive
+
+	// Test
+	err := testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_up",
+		"directadmin_scrape_duration_seconds",
+		"directadmin_last_successful_scrape_timestamp_seconds",
+		"directadmin_scrape_errors_total")
+	assert.Nil(t, err)
+}
+
+// TestClientAdminStatsErrorReasons is a unit test for the reasons of
+// the errors returned by the Client.AdminStats method.
+func TestClientAdminStatsErrorReasons(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Define tests
+	tests := []struct {
+		responder httpmock.Responder
+		expected  string
+		err       error
+	}{
+		{
+			responder: httpmock.NewErrorResponder(errors.New("refused")),
+			expected:  ReasonNetwork,
+		},
+		{
+			responder: httpmock.NewStringResponder(401, ""),
+			expected:  ReasonAuth,
+			err:       ErrUnauthorized,
+		},
+		{
+			responder: httpmock.NewStringResponder(403, "Forbidden"),
+			expected:  ReasonAuth,
+			err:       ErrUnauthorized,
+		},
+		{
+			responder: httpmock.NewStringResponder(503, ""),
+			expected:  ReasonHTTP,
+			err:       ErrHTTPStatus,
+		},
+		{
+			responder: httpmock.NewStringResponder(200,
+				responseFromFile("../testing/api/invalid-token.json")),
+			expected: ReasonAuth,
+			err:      ErrUnauthorized,
+		},
+		{
+			responder: httpmock.NewStringResponder(200,
+				`{"error": "1", "text": "Cannot show the stats"}`),
+			expected: ReasonAPI,
+			err:      ErrAPIError,
+		},
+		{
+			responder: httpmock.NewStringResponder(200, "<html></html>"),
+			expected:  ReasonParse,
+			err:       ErrNotJSON,
+		},
+	}
+
+	// Perform tests
+	url := statsURL(config)
+	for _, test := range tests {
+		httpmock.RegisterResponder("GET", url, test.responder)
+		_, err := NewClient(config).AdminStats(context.Background())
+		assert.Equal(t, test.expected, ErrorReason(err))
+		if test.err != nil {
+			assert.ErrorIs(t, err, test.err)
+		}
+	}
+}
+
+// TestAdminStatsCollectorCircuitBreaker is a unit test for the circuit
+// breaker of the AdminStatsCollector.
+//
+// It registers a failed response and updates the collector until
+// the circuit opens. The function verifies that the API is not requested
+// while the circuit is open and that the circuit state is exported.
+func TestAdminStatsCollectorCircuitBreaker(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(500, ""))
+
+	// Mock the current time
+	now := time.Unix(1688682917, 0)
+	timeNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	// Define configuration
+	breaking := config
+	breaking.CircuitBreaker = CircuitBreakerConfiguration{
+		Threshold: 2,
+		Cooldown:  time.Minute,
+	}
+	collector := NewAdminStatsCollector(breaking)
+
+	// Expected metrics
+	expected := func(open int) string {
+		return fmt.Sprintf(`
+# HELP directadmin_target_circuit_open Whether the requests to the target are stopped by the circuit breaker.
+# TYPE directadmin_target_circuit_open gauge
+directadmin_target_circuit_open %d
+`, open) // nolint: revive
+	}
+
+	// Failures opening the circuit
+	assert.ErrorIs(t, collector.Update(context.Background()), ErrHTTPStatus)
+	assert.ErrorIs(t, collector.Update(context.Background()), ErrHTTPStatus)
+	assert.ErrorIs(t, collector.Update(context.Background()), ErrCircuitOpen)
+	assert.Equal(t, 2, httpmock.GetTotalCallCount())
+	assert.Nil(t, testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected(1)), "directadmin_target_circuit_open"))
+
+	// Request after the cooldown
+	now = now.Add(time.Minute)
+	assert.Nil(t, testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected(0)), "directadmin_target_circuit_open"))
+	assert.ErrorIs(t, collector.Update(context.Background()), ErrHTTPStatus)
+	assert.Equal(t, 3, httpmock.GetTotalCallCount())
+}
+
+// TestAdminStatsCollectorURLEncoded is a unit test for the AdminStatsCollector
+// with a response of the legacy URL-encoded format.
+//
+// It registers a URL-encoded response and updates the collector. The function
+// verifies that the metrics match the ones of the JSON format.
+func TestAdminStatsCollectorURLEncoded(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register response
+	response := httpmock.NewStringResponse(200,
+		responseFromFile("../testing/api/successful.txt"))
+	response.Header.Set("Content-Type", "text/plain")
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.ResponderFromResponse(response))
+
+	// Update metrics
+	collector := NewAdminStatsCollector(config)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Expected metrics
+	expected := `
+# HELP directadmin_filesystem_used_bytes Filesystem used space in bytes (DirectAdmin fields: diskN).
+# TYPE directadmin_filesystem_used_bytes gauge
+directadmin_filesystem_used_bytes{device="/dev/sda1",mountpoint="/"} 4.4023754752e+10
+# HELP directadmin_nusers Number of users (DirectAdmin field: nusers).
+# TYPE directadmin_nusers gauge
+directadmin_nusers 211
+` // nolint: revive
 
-	// Metrics should be empty
-	assert.Equal(t, map[string]prometheus.Gauge{}, metrics)
+	// Test
+	err := testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_nusers",
+		"directadmin_filesystem_used_bytes")
+	assert.Nil(t, err)
 }
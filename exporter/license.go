@@ -0,0 +1,160 @@
+package exporter
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// licenseLabels lists the labels of the license info metric.
+var licenseLabels = []string{"type", "users"}
+
+// expiryLayouts lists the layouts of the expiry dates of the license, when it
+// isn't given as a Unix time.
+var expiryLayouts = []string{time.RFC3339, time.DateTime, time.DateOnly}
+
+// LicenseCollectorConfiguration represents the settings of the license
+// collector. It is disabled by default.
+type LicenseCollectorConfiguration struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// LicenseStats represents the license of the server. Expiry and Active are
+// nil when DirectAdmin doesn't report them.
+type LicenseStats struct {
+	// Expiry is the Unix time the license expires at.
+	Expiry *float64
+	Active *float64
+
+	// Type and Users are the license type and the maximum number of users.
+	Type  string
+	Users string
+}
+
+// NewLicenseStats returns the LicenseStats read from the parsed response of
+// the CMD_API_LICENSE command.
+func NewLicenseStats(response map[string]interface{}) LicenseStats {
+	stats := LicenseStats{
+		Expiry: parseExpiry(response["expiry"]),
+		Active: parseState(response["active"]),
+		Users:  labelValue(response["users"]),
+	}
+	stats.Type, _ = response["type"].(string)
+	return stats
+}
+
+// Valid reports whether the license is valid at the provided time, and
+// whether its validity is known. It is valid unless DirectAdmin reports it
+// inactive or it has expired, and unknown when DirectAdmin reports neither
+// its state nor its expiry.
+func (l LicenseStats) Valid(now time.Time) (valid, known bool) {
+	if l.Expiry == nil && l.Active == nil {
+		return false, false
+	}
+	if l.Active != nil && *l.Active == 0 {
+		return false, true
+	}
+	return l.Expiry == nil ||
+		float64(now.UnixNano())/float64(time.Second) < *l.Expiry, true
+}
+
+// parseExpiry returns the Unix time of the expiry of the license, given as
+// a Unix time or as a date, or nil if it cannot be parsed.
+func parseExpiry(expiry interface{}) *float64 {
+	if seconds, ok := toFloat(expiry); ok {
+		return &seconds
+	}
+
+	text, _ := expiry.(string)
+	for _, layout := range expiryLayouts {
+		if date, err := time.Parse(layout, text); err == nil {
+			seconds := float64(date.Unix())
+			return &seconds
+		}
+	}
+	return nil
+}
+
+// labelValue returns the label value of a string or a number of the API
+// response, or an empty string for other values.
+func labelValue(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+// licenseCollector is the subcollector exporting the license of the server.
+type licenseCollector struct {
+	client  *Client
+	mutex   sync.RWMutex
+	license *LicenseStats
+
+	// Metric descriptions
+	expiry *prometheus.Desc
+	valid  *prometheus.Desc
+	info   *prometheus.Desc
+}
+
+// newLicenseCollector returns a new licenseCollector requesting the API with
+// the client.
+func newLicenseCollector(config APIConfiguration,
+	client *Client) *licenseCollector {
+	return &licenseCollector{
+		client: client,
+		expiry: prometheus.NewDesc(
+			"directadmin_license_expiry_timestamp_seconds",
+			"Unix time the DirectAdmin license expires at "+
+				"(DirectAdmin field: expiry).", nil, config.Labels),
+		valid: prometheus.NewDesc("directadmin_license_valid",
+			"Whether the DirectAdmin license is active and not expired "+
+				"(DirectAdmin fields: active, expiry).", nil, config.Labels),
+		info: prometheus.NewDesc("directadmin_license_info",
+			"License type and maximum number of users of the DirectAdmin "+
+				"license, the value is always 1 (DirectAdmin fields: type, "+
+				"users).", licenseLabels, config.Labels),
+	}
+}
+
+// update retrieves the license of the server.
+func (l *licenseCollector) update(ctx context.Context) error {
+	response, err := l.client.License(ctx)
+	var license *LicenseStats
+	if err == nil {
+		stats := NewLicenseStats(response)
+		license = &stats
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.license = license
+	return err
+}
+
+// collect sends the metrics of the license. The validity is computed at
+// scrape time, so an expiry is noticed between the updates, and it is not
+// sent when it is unknown.
+func (l *licenseCollector) collect(ch chan<- prometheus.Metric) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	if l.license == nil {
+		return
+	}
+	if l.license.Expiry != nil {
+		ch <- prometheus.MustNewConstMetric(l.expiry, prometheus.GaugeValue,
+			*l.license.Expiry)
+	}
+	if valid, known := l.license.Valid(timeNow()); known {
+		ch <- prometheus.MustNewConstMetric(l.valid, prometheus.GaugeValue,
+			boolToFloat(valid))
+	}
+	ch <- prometheus.MustNewConstMetric(l.info, prometheus.GaugeValue, 1,
+		l.license.Type, l.license.Users)
+}
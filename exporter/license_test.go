@@ -0,0 +1,267 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// licenseURL is the URL of the legacy API command returning the license.
+const licenseURL = "http://localhost:2222/CMD_API_LICENSE?json=yes"
+
+// TestNewLicenseStats is a unit test for the NewLicenseStats function.
+//
+// It reads licenses with various expiry formats. The function verifies
+// the expiry, the state and the info of the licenses.
+func TestNewLicenseStats(t *testing.T) {
+	// Define states
+	expiry, active := 1719792000.0, 1.0
+
+	// Define tests
+	tests := []struct {
+		name     string
+		response map[string]interface{}
+		expected LicenseStats
+	}{
+		{
+			name: "Unix time",
+			response: map[string]interface{}{
+				"expiry": "1719792000",
+				"active": "yes",
+				"type":   "Personal Plus",
+				"users":  "10",
+			},
+			expected: LicenseStats{
+				Expiry: &expiry,
+				Active: &active,
+				Type:   "Personal Plus",
+				Users:  "10",
+			},
+		},
+		{
+			name: "Numbers",
+			response: map[string]interface{}{
+				"expiry": 1719792000.0,
+				"users":  10.0,
+			},
+			expected: LicenseStats{Expiry: &expiry, Users: "10"},
+		},
+		{
+			name:     "Date",
+			response: map[string]interface{}{"expiry": "2024-07-01"},
+			expected: LicenseStats{Expiry: &expiry},
+		},
+		{
+			name: "Date and time",
+			response: map[string]interface{}{
+				"expiry": "2024-07-01T00:00:00Z",
+			},
+			expected: LicenseStats{Expiry: &expiry},
+		},
+		{
+			name: "Invalid expiry",
+			response: map[string]interface{}{
+				"expiry": "soon",
+				"users":  []interface{}{},
+			},
+			expected: LicenseStats{},
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		assert.Equal(t, test.expected, NewLicenseStats(test.response),
+			test.name)
+	}
+}
+
+// TestLicenseStatsValid is a unit test for the LicenseStats.Valid method.
+func TestLicenseStatsValid(t *testing.T) {
+	// Define states
+	expiry, active, inactive := 1719792000.0, 1.0, 0.0
+	now := time.Unix(1719791999, 0)
+
+	// Define tests
+	tests := []struct {
+		name    string
+		license LicenseStats
+		now     time.Time
+		valid   bool
+		known   bool
+	}{
+		{
+			name:    "Active",
+			license: LicenseStats{Expiry: &expiry, Active: &active},
+			now:     now,
+			valid:   true,
+			known:   true,
+		},
+		{
+			name:    "Expired",
+			license: LicenseStats{Expiry: &expiry, Active: &active},
+			now:     now.Add(time.Second),
+			valid:   false,
+			known:   true,
+		},
+		{
+			name:    "Inactive",
+			license: LicenseStats{Expiry: &expiry, Active: &inactive},
+			now:     now,
+			valid:   false,
+			known:   true,
+		},
+		{
+			name:    "Unknown expiry",
+			license: LicenseStats{Active: &active},
+			now:     now,
+			valid:   true,
+			known:   true,
+		},
+		{
+			name:    "Unknown state and expiry",
+			license: LicenseStats{},
+			now:     now,
+			valid:   false,
+			known:   false,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		valid, known := test.license.Valid(test.now)
+		assert.Equal(t, test.valid, valid, test.name)
+		assert.Equal(t, test.known, known, test.name)
+	}
+}
+
+// TestLicenseCollector is a unit test for the license collector of
+// the AdminStatsCollector.
+//
+// It registers the response of the license and updates the collector.
+// The function verifies the license metrics and that the validity is
+// computed at scrape time.
+func TestLicenseCollector(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/successful.json")))
+	httpmock.RegisterResponder("GET", licenseURL,
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/license.json")))
+
+	// Mock the current time
+	now := time.Unix(1719791000, 0)
+	timeNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	// Update metrics
+	license := config
+	license.License = LicenseCollectorConfiguration{Enabled: true}
+	collector := NewAdminStatsCollector(license)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Expected metrics
+	expected := func(valid int) string {
+		return fmt.Sprintf(`
+# HELP directadmin_license_expiry_timestamp_seconds Unix time the DirectAdmin license expires at (DirectAdmin field: expiry).
+# TYPE directadmin_license_expiry_timestamp_seconds gauge
+directadmin_license_expiry_timestamp_seconds 1.719792e+09
+# HELP directadmin_license_info License type and maximum number of users of the DirectAdmin license, the value is always 1 (DirectAdmin fields: type, users).
+# TYPE directadmin_license_info gauge
+directadmin_license_info{type="Personal Plus",users="10"} 1
+# HELP directadmin_license_valid Whether the DirectAdmin license is active and not expired (DirectAdmin fields: active, expiry).
+# TYPE directadmin_license_valid gauge
+directadmin_license_valid %d
+`, valid) // nolint: revive
+	}
+
+	// Test
+	err := testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected(1)),
+		"directadmin_license_expiry_timestamp_seconds",
+		"directadmin_license_info", "directadmin_license_valid")
+	assert.Nil(t, err)
+
+	// Scrape after the expiry
+	now = now.Add(time.Hour)
+	err = testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected(0)),
+		"directadmin_license_valid")
+	assert.Nil(t, err)
+}
+
+// TestLicenseCollectorError is a unit test for a failed request of
+// the license collector.
+//
+// It registers a failed response of the license and updates the collector.
+// The function verifies that the error is counted and that the license
+// metrics are not exported.
+func TestLicenseCollectorError(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/successful.json")))
+	httpmock.RegisterResponder("GET", licenseURL,
+		httpmock.NewStringResponder(200, "<html></html>"))
+
+	// Update metrics
+	license := config
+	license.License = LicenseCollectorConfiguration{Enabled: true}
+	collector := NewAdminStatsCollector(license)
+	assert.ErrorIs(t, collector.Update(context.Background()), ErrNotJSON)
+
+	// Test
+	assert.Equal(t, 1.0, collector.errors[ReasonParse])
+	assert.Equal(t, 1.0, collector.up)
+	count, err := testutil.GatherAndCount(collector.Registry(),
+		"directadmin_license_valid", "directadmin_license_info")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+// TestLicenseCollectorUnknown is a unit test for a license of unknown
+// validity.
+//
+// It registers a license response with neither the state nor the expiry and
+// updates the collector. The function verifies that the license info is
+// exported, but not the validity.
+func TestLicenseCollectorUnknown(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/successful.json")))
+	httpmock.RegisterResponder("GET", licenseURL,
+		httpmock.NewStringResponder(200, `{"type": "Personal Plus"}`))
+
+	// Update metrics
+	license := config
+	license.License = LicenseCollectorConfiguration{Enabled: true}
+	collector := NewAdminStatsCollector(license)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Test
+	for name, expected := range map[string]int{
+		"directadmin_license_info":  1,
+		"directadmin_license_valid": 0,
+	} {
+		count, err := testutil.GatherAndCount(collector.Registry(), name)
+		assert.Nil(t, err, name)
+		assert.Equal(t, expected, count, name)
+	}
+}
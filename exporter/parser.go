@@ -1,12 +1,51 @@
 package exporter
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/url"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+// diskKeyRegexp matches the keys of the filesystem rows in the response.
+var diskKeyRegexp = regexp.MustCompile(`^disk[0-9]+$`)
+
+// urlEncodedPairRegexp matches a key=value pair of a URL-encoded response.
+// Markup and whitespace are escaped in such responses, so they reject
+// the HTML login page of the panel.
+var urlEncodedPairRegexp = regexp.MustCompile(`^[^=&<>\s]+=[^&<>\s]*$`)
+
+// blockSize is the size of the blocks the filesystem rows are reported in.
+const blockSize = 1024
+
+// unlimitedValue is the value DirectAdmin reports for unlimited allocations.
+const unlimitedValue = "unlimited"
+
+// Allocation represents a resource allocation of the DirectAdmin API
+// response. The value of an unlimited allocation is +Inf.
+type Allocation struct {
+	Resource  string
+	Value     float64
+	Unlimited bool
+}
+
+// Filesystem represents a filesystem row of the DirectAdmin API response.
+type Filesystem struct {
+	Device     string
+	Mountpoint string
+	Size       float64
+	Used       float64
+	Avail      float64
+}
+
 // toMetricName converts a metric name to a valid Prometheus metric name.
 func toMetricName(name string) string {
 	m := regexp.MustCompile("[^A-Za-z0-9]+")
@@ -14,31 +53,281 @@ func toMetricName(name string) string {
 }
 
 // ParseResponse parses the DirectAdmin API response into a map of
-// string-interface{}.
+// string-interface{}. A top-level array, returned by the REST API for
+// lists, is stored under the list key, like the arrays of the URL-encoded
+// format.
 func ParseResponse(response []byte) (map[string]interface{}, error) {
-	var data map[string]interface{}
-	err := json.Unmarshal(response, &data)
-	return data, err
+	var data interface{}
+	if err := json.Unmarshal(response, &data); err != nil {
+		return nil, err
+	}
+
+	switch data := data.(type) {
+	case map[string]interface{}:
+		return data, nil
+	case []interface{}:
+		return map[string]interface{}{"list": data}, nil
+	}
+	return nil, errors.New("response is neither an object nor an array")
+}
+
+// ParseURLEncodedResponse parses a DirectAdmin API response of the legacy
+// URL-encoded format, key=value pairs separated by ampersands. The values of
+// the keys suffixed with [] are collected into arrays, so the result has
+// the shape of a parsed JSON response. Bodies which are not key=value pairs,
+// such as an HTML page, are rejected.
+func ParseURLEncodedResponse(response []byte) (map[string]interface{},
+	error) {
+	body := strings.TrimSpace(string(response))
+	if err := checkURLEncoded(body); err != nil {
+		return nil, err
+	}
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]interface{}, len(values))
+	for key, list := range values {
+		if name, isArray := strings.CutSuffix(key, "[]"); isArray {
+			items := make([]interface{}, len(list))
+			for i, item := range list {
+				items[i] = item
+			}
+			data[name] = items
+			continue
+		}
+		data[key] = list[0]
+	}
+	return data, nil
+}
+
+// checkURLEncoded checks that the body is made of key=value pairs separated
+// by ampersands. An empty body has no pairs.
+func checkURLEncoded(body string) error {
+	if body == "" {
+		return nil
+	}
+	for _, pair := range strings.Split(body, "&") {
+		if !urlEncodedPairRegexp.MatchString(pair) {
+			return fmt.Errorf("invalid key=value pair %q", pair)
+		}
+	}
+	return nil
+}
+
+// Response formats of the DirectAdmin API.
+const (
+	FormatAuto       = "auto"
+	FormatJSON       = "json"
+	FormatURLEncoded = "urlencoded"
+)
+
+// responseFormats lists the valid response formats of a target, the empty
+// format is FormatAuto.
+var responseFormats = []string{"", FormatAuto, FormatJSON, FormatURLEncoded}
+
+// urlEncodedTypes lists the media types of the URL-encoded responses.
+var urlEncodedTypes = []string{"text/plain",
+	"application/x-www-form-urlencoded"}
+
+// responseParser represents the parser of a response format and the error
+// returned when the response cannot be parsed.
+type responseParser struct {
+	parse func([]byte) (map[string]interface{}, error)
+	err   error
+}
+
+// responseParsers maps the response formats to their parsers.
+var responseParsers = map[string]responseParser{
+	FormatJSON:       {parse: ParseResponse, err: ErrNotJSON},
+	FormatURLEncoded: {parse: ParseURLEncodedResponse, err: ErrNotURLEncoded},
+}
+
+// responseFormat returns the format of the response. The format of
+// the target is used unless it is FormatAuto, then the format is chosen from
+// the Content-Type header, defaulting to JSON. Bodies looking like JSON are
+// parsed as JSON whatever their Content-Type is.
+func responseFormat(format string, response Response) string {
+	if format != "" && format != FormatAuto {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(response.ContentType)
+	if slices.Contains(urlEncodedTypes, mediaType) &&
+		!looksLikeJSON(response.Body) {
+		return FormatURLEncoded
+	}
+	return FormatJSON
+}
+
+// looksLikeJSON reports whether the body is a JSON object or array.
+func looksLikeJSON(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	return len(body) > 0 && (body[0] == '{' || body[0] == '[')
+}
+
+// ConvertOptions represents the options of the response conversion.
+type ConvertOptions struct {
+	// IndexArrays converts array elements into values suffixed with their
+	// indexes. Arrays are skipped otherwise.
+	IndexArrays bool
 }
 
 // ConvertResponse converts the parsed API response into
-// a map of string-float64.
-func ConvertResponse(response map[string]interface{}) map[string]float64 {
+// a map of string-float64. Numbers are used directly, booleans are
+// converted to 0 or 1 and non-numeric strings are dropped. It also returns
+// the number of skipped fields: nulls, unknown types and skipped arrays.
+func ConvertResponse(response map[string]interface{},
+	options ConvertOptions) (map[string]float64, int) {
 	data := make(map[string]float64)
+	skipped := 0
 	for key, value := range response {
-		switch value := value.(type) {
-		case string:
-			float, err := strconv.ParseFloat(value, 64)
-			if err == nil {
-				data[toMetricName(key)] = float
-			}
-		case map[string]interface{}:
-			for k, v := range ConvertResponse(value) {
-				data[toMetricName(key+"_"+k)] = v
-			}
-		default:
-			panic("Unexpected data type")
+		skipped += convertValue(toMetricName(key), value, data, options)
+	}
+	return data, skipped
+}
+
+// convertValue stores the converted value under the provided key in data
+// and returns the number of skipped fields.
+func convertValue(key string, value interface{}, data map[string]float64,
+	options ConvertOptions) int {
+	switch value := value.(type) {
+	case string:
+		float, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			data[key] = float
+		}
+	case float64:
+		data[key] = value
+	case bool:
+		data[key] = boolToFloat(value)
+	case map[string]interface{}:
+		return convertMap(key, value, data, options)
+	case []interface{}:
+		if !options.IndexArrays {
+			return 1
+		}
+		return convertArray(key, value, data, options)
+	default:
+		return 1
+	}
+	return 0
+}
+
+// convertMap stores the converted values of a nested object prefixed with
+// the provided key and returns the number of skipped fields.
+func convertMap(key string, value map[string]interface{},
+	data map[string]float64, options ConvertOptions) int {
+	skipped := 0
+	for k, v := range value {
+		skipped += convertValue(toMetricName(key+"_"+k), v, data, options)
+	}
+	return skipped
+}
+
+// convertArray stores the converted array elements suffixed with their
+// indexes and returns the number of skipped fields.
+func convertArray(key string, value []interface{}, data map[string]float64,
+	options ConvertOptions) int {
+	skipped := 0
+	for i, v := range value {
+		skipped += convertValue(fmt.Sprintf("%s_%d", key, i), v, data,
+			options)
+	}
+	return skipped
+}
+
+// ParseFilesystem parses a colon-separated filesystem row such as
+// "/dev/sdb:655261800:589609332:38877088:94%:/home". Sizes are reported in
+// 1024-blocks and converted to bytes.
+func ParseFilesystem(row string) (Filesystem, error) {
+	// Split the row into device, size, used, available, capacity and
+	// mountpoint columns
+	columns := strings.SplitN(row, ":", 6)
+	if len(columns) != 6 {
+		return Filesystem{}, fmt.Errorf("invalid filesystem row %q", row)
+	}
+
+	// Convert sizes
+	sizes := make([]float64, 3)
+	for i, column := range columns[1:4] {
+		value, err := strconv.ParseFloat(column, 64)
+		if err != nil {
+			return Filesystem{}, fmt.Errorf("invalid filesystem row %q",
+				row)
+		}
+		sizes[i] = value * blockSize
+	}
+
+	return Filesystem{
+		Device:     columns[0],
+		Mountpoint: columns[5],
+		Size:       sizes[0],
+		Used:       sizes[1],
+		Avail:      sizes[2],
+	}, nil
+}
+
+// ParseFilesystems returns the filesystems listed in the diskN fields of
+// the parsed API response, sorted by the field names. Rows which cannot be
+// parsed and devices matching the exclude expression are skipped.
+func ParseFilesystems(response map[string]interface{},
+	exclude *regexp.Regexp) []Filesystem {
+	// Find filesystem rows
+	keys := []string{}
+	for key := range response {
+		if diskKeyRegexp.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return diskIndex(keys[i]) < diskIndex(keys[j])
+	})
+
+	// Parse filesystem rows
+	filesystems := []Filesystem{}
+	for _, key := range keys {
+		row, _ := response[key].(string)
+		fs, err := ParseFilesystem(row)
+		if err != nil || exclude.MatchString(fs.Device) {
+			continue
+		}
+		filesystems = append(filesystems, fs)
+	}
+	return filesystems
+}
+
+// diskIndex returns the number of the diskN field.
+func diskIndex(key string) int {
+	index, _ := strconv.Atoi(strings.TrimPrefix(key, "disk"))
+	return index
+}
+
+// ParseAllocations returns the resource allocations listed in the allocated
+// field of the parsed API response, sorted by resource names. Values which
+// are neither numbers nor "unlimited" are skipped.
+func ParseAllocations(response map[string]interface{}) []Allocation {
+	allocated, _ := response["allocated"].(map[string]interface{})
+
+	allocations := []Allocation{}
+	for resource, value := range allocated {
+		if value == unlimitedValue {
+			allocations = append(allocations, Allocation{
+				Resource:  toMetricName(resource),
+				Value:     math.Inf(1),
+				Unlimited: true,
+			})
+			continue
+		}
+		if float, ok := toFloat(value); ok {
+			allocations = append(allocations, Allocation{
+				Resource: toMetricName(resource),
+				Value:    float,
+			})
 		}
 	}
-	return data
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].Resource < allocations[j].Resource
+	})
+	return allocations
 }
@@ -1,7 +1,9 @@
 package exporter
 
 import (
-	"fmt"
+	"context"
+	"math"
+	"regexp"
 	"testing"
 
 	"github.com/jarcoal/httpmock"
@@ -11,7 +13,7 @@ import (
 // TestParseResponse is a unit test for the ParseResponse function.
 //
 // It activates the HTTP mock, configures the response, registers
-// the response function, gets the metrics using the GetMetrics function.
+// the response function, gets the metrics using the ParseResponse function.
 // The function verifies that the metrics fetched from the API match
 // the expected values.
 func TestParseResponse(t *testing.T) {
@@ -119,15 +121,15 @@ func TestParseResponse(t *testing.T) {
 	defer httpmock.DeactivateAndReset()
 
 	// Register response
-	httpmock.RegisterResponder("GET", fmt.Sprintf(urlFormat,
-		config.Protocol, config.Username, config.Token, config.Hostname,
-		config.Port), responseFunction(mockResponse))
+	httpmock.RegisterResponder("GET", statsURL(config),
+		responseFunction(mockResponse))
 
 	// Make request
-	response, _ := APIRequest(config)
+	response, _ := NewClient(config).Request(context.Background(),
+		commandAdminStats)
 
 	// Parse request
-	parsed, err := ParseResponse(response)
+	parsed, err := ParseResponse(response.Body)
 
 	// Asserts
 	assert.Nil(t, err)
@@ -187,19 +189,272 @@ func TestConvertResponse(t *testing.T) {
 
 	// Perform tests
 	for _, test := range tests {
-		assert.Equal(t, test.expected, ConvertResponse(test.given))
+		given, skipped := ConvertResponse(test.given, ConvertOptions{})
+		assert.Equal(t, test.expected, given)
+		assert.Equal(t, 0, skipped)
 	}
 }
 
-// TestConvertResponseInvalidType tests the ConvertResponse function with
-// an invalid type.
-func TestConvertResponseInvalidType(t *testing.T) {
+// TestConvertResponseTypes tests the ConvertResponse function with all
+// the JSON types.
+func TestConvertResponseTypes(t *testing.T) {
 	// Define testing data
 	given := map[string]interface{}{
 		"bandwidth":  "85541",
+		"number":     12.5,
+		"enabled":    true,
+		"disabled":   false,
+		"null":       nil,
+		"array":      []interface{}{"1", 2.0, nil},
 		"unexpected": []struct{}{},
 	}
 
+	// Define tests
+	tests := []struct {
+		options  ConvertOptions
+		expected map[string]float64
+		skipped  int
+	}{
+		{
+			options: ConvertOptions{},
+			expected: map[string]float64{
+				"bandwidth": 85541,
+				"number":    12.5,
+				"enabled":   1,
+				"disabled":  0,
+			},
+			skipped: 3,
+		},
+		{
+			options: ConvertOptions{IndexArrays: true},
+			expected: map[string]float64{
+				"bandwidth": 85541,
+				"number":    12.5,
+				"enabled":   1,
+				"disabled":  0,
+				"array_0":   1,
+				"array_1":   2,
+			},
+			skipped: 3,
+		},
+	}
+
+	// Perform tests
+	for _, test := range tests {
+		data, skipped := ConvertResponse(given, test.options)
+		assert.Equal(t, test.expected, data)
+		assert.Equal(t, test.skipped, skipped)
+	}
+}
+
+// TestParseFilesystem tests the ParseFilesystem function.
+func TestParseFilesystem(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		given    string
+		expected Filesystem
+		err      bool
+	}{
+		{
+			given: "/dev/sdb:655261800:589609332:38877088:94%:/home",
+			expected: Filesystem{
+				Device:     "/dev/sdb",
+				Mountpoint: "/home",
+				Size:       655261800 * 1024,
+				Used:       589609332 * 1024,
+				Avail:      38877088 * 1024,
+			},
+		},
+		{
+			given: "/dev/sda1:10:5:5:50%:/mnt/a:b",
+			expected: Filesystem{
+				Device:     "/dev/sda1",
+				Mountpoint: "/mnt/a:b",
+				Size:       10 * 1024,
+				Used:       5 * 1024,
+				Avail:      5 * 1024,
+			},
+		},
+		{given: "/dev/sdb:655261800", err: true},
+		{given: "/dev/sdb:x:589609332:38877088:94%:/home", err: true},
+	}
+
+	// Perform tests
+	for _, test := range tests {
+		fs, err := ParseFilesystem(test.given)
+		assert.Equal(t, test.expected, fs, test.given)
+		assert.Equal(t, test.err, err != nil, test.given)
+	}
+}
+
+// TestParseFilesystems tests the ParseFilesystems function.
+func TestParseFilesystems(t *testing.T) {
+	// Define testing data
+	given := map[string]interface{}{
+		"disk10":    "/dev/sdc:2:1:1:50%:/volume",
+		"disk1":     "devtmpfs:7922696:0:7922696:0%:/dev",
+		"disk2":     "/dev/sda1:4:2:2:50%:/",
+		"disk3":     "invalid",
+		"disk":      map[string]interface{}{},
+		"bandwidth": "85541",
+	}
+
 	// Perform test
-	assert.Panics(t, func() { ConvertResponse(given) })
+	filesystems := ParseFilesystems(given,
+		regexp.MustCompile(defaultFilesystemExclude))
+
+	// Test
+	assert.Equal(t, []Filesystem{
+		{Device: "/dev/sda1", Mountpoint: "/", Size: 4096, Used: 2048,
+			Avail: 2048},
+		{Device: "/dev/sdc", Mountpoint: "/volume", Size: 2048, Used: 1024,
+			Avail: 1024},
+	}, filesystems)
+}
+
+// TestParseAllocations tests the ParseAllocations function.
+func TestParseAllocations(t *testing.T) {
+	// Define testing data
+	given := map[string]interface{}{
+		"allocated": map[string]interface{}{
+			"quota":     "845790",
+			"bandwidth": "unlimited",
+			"invalid":   "n/a",
+		},
+	}
+
+	// Test
+	assert.Equal(t, []Allocation{
+		{Resource: "bandwidth", Value: math.Inf(1), Unlimited: true},
+		{Resource: "quota", Value: 845790},
+	}, ParseAllocations(given))
+	assert.Equal(t, []Allocation{},
+		ParseAllocations(map[string]interface{}{}))
+}
+
+// TestParseURLEncodedResponse is a unit test for the ParseURLEncodedResponse
+// function.
+func TestParseURLEncodedResponse(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		name     string
+		response string
+		expected map[string]interface{}
+		valid    bool
+	}{
+		{
+			name:     "Key value pairs",
+			response: "nusers=211&device=eth0%3A1&text=Not+logged+in\n",
+			expected: map[string]interface{}{
+				"nusers": "211",
+				"device": "eth0:1",
+				"text":   "Not logged in",
+			},
+			valid: true,
+		},
+		{
+			name:     "Arrays",
+			response: "list[]=admin&list[]=reseller&list%5B%5D=user",
+			expected: map[string]interface{}{
+				"list": []interface{}{"admin", "reseller", "user"},
+			},
+			valid: true,
+		},
+		{
+			name:     "Repeated key",
+			response: "nusers=1&nusers=2",
+			expected: map[string]interface{}{"nusers": "1"},
+			valid:    true,
+		},
+		{
+			name:     "Empty response",
+			response: "",
+			expected: map[string]interface{}{},
+			valid:    true,
+		},
+		{
+			name:     "Invalid escape",
+			response: "nusers=%zz",
+			valid:    false,
+		},
+		{
+			name: "Login page",
+			response: "<html>\n<head><title>DirectAdmin Login</title>" +
+				"</head>\n<body><form action=\"/CMD_LOGIN\" " +
+				"method=\"post\"></form></body>\n</html>\n",
+			valid: false,
+		},
+		{
+			name:     "No key value pairs",
+			response: "Not logged in",
+			valid:    false,
+		},
+		{
+			name:     "Missing key",
+			response: "nusers=211&=eth0",
+			valid:    false,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		parsed, err := ParseURLEncodedResponse([]byte(test.response))
+		if test.valid {
+			assert.Nil(t, err, test.name)
+			assert.Equal(t, test.expected, parsed, test.name)
+		} else {
+			assert.Error(t, err, test.name)
+		}
+	}
+}
+
+// TestResponseFormat is a unit test for the responseFormat function.
+func TestResponseFormat(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		format      string
+		contentType string
+		body        string
+		expected    string
+	}{
+		{"", "application/json", "{}", FormatJSON},
+		{"", "text/plain; charset=utf-8", "a=1", FormatURLEncoded},
+		{"", "text/plain", " {\"a\": 1}", FormatJSON},
+		{"", "text/plain", "[]", FormatJSON},
+		{"", "text/plain", "", FormatURLEncoded},
+		{FormatAuto, "application/x-www-form-urlencoded", "a=1",
+			FormatURLEncoded},
+		{FormatAuto, "text/html", "<html>", FormatJSON},
+		{FormatAuto, "", "", FormatJSON},
+		{FormatJSON, "text/plain", "a=1", FormatJSON},
+		{FormatURLEncoded, "application/json", "{}", FormatURLEncoded},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		response := Response{
+			ContentType: test.contentType,
+			Body:        []byte(test.body),
+		}
+		assert.Equal(t, test.expected,
+			responseFormat(test.format, response),
+			"%s %s %s", test.format, test.contentType, test.body)
+	}
+}
+
+// TestParseResponseShapes is a unit test for the ParseResponse function with
+// responses of other shapes than an object.
+func TestParseResponseShapes(t *testing.T) {
+	// Top-level array
+	parsed, err := ParseResponse([]byte(`[{"username": "admin"}]`))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"list": []interface{}{
+		map[string]interface{}{"username": "admin"},
+	}}, parsed)
+
+	// Scalars
+	for _, response := range []string{`"text"`, `1`, `null`} {
+		_, err := ParseResponse([]byte(response))
+		assert.Error(t, err, response)
+	}
 }
@@ -0,0 +1,57 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// probeTarget represents the collector of a target of the probes. Its mutex
+// serializes the probes of the target, so every response is served from
+// the snapshot of its own update.
+type probeTarget struct {
+	mutex     sync.Mutex
+	collector *AdminStatsCollector
+}
+
+// ProbeHandler returns an HTTP handler answering /probe?target=<name>
+// requests. Metrics of the named target are retrieved on demand. Every
+// target has its own collector, so the connections, the error counters and
+// the circuit breaker are kept between probes. Concurrent probes of a target
+// wait for each other.
+func ProbeHandler(targets map[string]APIConfiguration) http.Handler {
+	probeTargets := make(map[string]*probeTarget)
+	for name, config := range targets {
+		probeTargets[name] = &probeTarget{
+			collector: NewAdminStatsCollector(config),
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Find target
+		name := r.URL.Query().Get("target")
+		if name == "" {
+			http.Error(w, "target parameter is missing",
+				http.StatusBadRequest)
+			return
+		}
+		target, exists := probeTargets[name]
+		if !exists {
+			http.Error(w, fmt.Sprintf("unknown target %q", name),
+				http.StatusNotFound)
+			return
+		}
+
+		// Get metrics, errors are logged by the client. The request to
+		// the API is canceled when the probe request is.
+		target.mutex.Lock()
+		defer target.mutex.Unlock()
+		_ = target.collector.Update(r.Context())
+
+		// Serve metrics
+		promhttp.HandlerFor(target.collector.Registry(),
+			promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
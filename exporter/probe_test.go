@@ -0,0 +1,141 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestProbeHandler tests the ProbeHandler function.
+//
+// It activates the HTTP mock, configures the response, registers
+// the response function and performs probe requests. The function verifies
+// the status codes and the metrics returned for the requested targets.
+func TestProbeHandler(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Configure response
+	var test = APIResponseTest{
+		Response: responseFromFile("../testing/api/successful.json"),
+		Status:   200,
+	}
+
+	// Register response
+	httpmock.RegisterResponder("GET", statsURL(config), responseFunction(test))
+
+	// Define tests
+	tests := []struct {
+		url      string
+		status   int
+		contains string
+	}{
+		{
+			url:      "/probe?target=server1",
+			status:   http.StatusOK,
+			contains: "directadmin_bandwidth_bytes_total 8.9696239616e+10",
+		},
+		{
+			url:      "/probe",
+			status:   http.StatusBadRequest,
+			contains: "target parameter is missing",
+		},
+		{
+			url:      "/probe?target=server2",
+			status:   http.StatusNotFound,
+			contains: `unknown target "server2"`,
+		},
+	}
+
+	// Perform tests
+	handler := ProbeHandler(map[string]APIConfiguration{"server1": config})
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, test.url, nil)
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, test.status, recorder.Code, test.url)
+		assert.Contains(t, recorder.Body.String(), test.contains, test.url)
+	}
+}
+
+// TestProbeHandlerKeepsCollectors tests that the ProbeHandler function keeps
+// the collector of a target between probes, so the counters accumulate.
+func TestProbeHandlerKeepsCollectors(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(500, ""))
+
+	// Perform probes
+	handler := ProbeHandler(map[string]APIConfiguration{"server1": config})
+	recorder := httptest.NewRecorder()
+	for i := 0; i < 2; i++ {
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/probe?target=server1", nil))
+	}
+
+	// Test
+	assert.Contains(t, recorder.Body.String(),
+		`directadmin_scrape_errors_total{reason="http"} 2`)
+}
+
+// TestProbeHandlerConcurrentProbes tests that the ProbeHandler function
+// serializes the probes of a target.
+//
+// It blocks the first request to the API and starts a second probe of
+// the target meanwhile. The function verifies that the second probe waits
+// for the first one, and that every probe is served the snapshot of its own
+// request.
+func TestProbeHandlerConcurrentProbes(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Register response, the first request waits for the release
+	var calls atomic.Int32
+	requested, release := make(chan struct{}), make(chan struct{})
+	httpmock.RegisterResponder("GET", statsURL(config),
+		func(*http.Request) (*http.Response, error) {
+			call := calls.Add(1)
+			if call == 1 {
+				close(requested)
+				<-release
+			}
+			return httpmock.NewStringResponse(200,
+				fmt.Sprintf(`{"nusers": "%d"}`, call)), nil
+		})
+
+	// Perform probes
+	handler := ProbeHandler(map[string]APIConfiguration{"server1": config})
+	recorders := []*httptest.ResponseRecorder{httptest.NewRecorder(),
+		httptest.NewRecorder()}
+	var wg sync.WaitGroup
+	probe := func(recorder *httptest.ResponseRecorder) {
+		defer wg.Done()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/probe?target=server1", nil))
+	}
+	wg.Add(2)
+	go probe(recorders[0])
+	<-requested
+	go probe(recorders[1])
+
+	// Test
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load())
+	close(release)
+	wg.Wait()
+	assert.Contains(t, recorders[0].Body.String(), "directadmin_nusers 1")
+	assert.Contains(t, recorders[1].Body.String(), "directadmin_nusers 2")
+}
@@ -0,0 +1,332 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultResellerInterval is the default interval between the walks of
+// the users of the resellers.
+const defaultResellerInterval = time.Hour
+
+// resellerFields lists the resources of the resellers whose usage and
+// allocation are exported.
+var resellerFields = []string{"bandwidth", "quota", "vdomains", "nusers"}
+
+// assignedFields lists the resources of the resellers which are allocated to
+// their users, so they can be oversold.
+var assignedFields = []string{"bandwidth", "quota", "vdomains"}
+
+// resellerLabels lists the labels of the metrics of the reseller collector.
+// Only the resource metric has all of them, the other metrics drop the last
+// ones.
+var resellerLabels = []string{"reseller", "resource", "kind"}
+
+// ResellerCollectorConfiguration represents the settings of the reseller
+// collector. It is disabled by default, as every reseller costs three
+// requests, and one more per user of the reseller.
+type ResellerCollectorConfiguration struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Interval is the time between the walks of the users of the resellers,
+	// which sum the allocations assigned to them. The sums of the last walk
+	// are exported in the meantime.
+	Interval time.Duration `yaml:"interval" validate:"gte=0"`
+
+	// Timeout is the timeout of a walk of the users, separate from
+	// the timeout of the updates, as the users are walked in the background.
+	Timeout time.Duration `yaml:"timeout" validate:"gte=0"`
+}
+
+// ResellerStats represents the usage and the limits of a reseller.
+type ResellerStats struct {
+	Name string
+
+	// Usage holds the usage fields listed in resellerFields, indexed by
+	// the DirectAdmin field names.
+	Usage       map[string]float64
+	Allocations []Allocation
+
+	// Assigned holds the sums of the allocations of the users of
+	// the reseller for the fields listed in assignedFields, +Inf if one of
+	// them is unlimited, nil until the users of the reseller are walked.
+	Assigned map[string]float64
+
+	// Oversell is whether the reseller is allowed to oversell, nil when
+	// DirectAdmin doesn't report it.
+	Oversell *float64
+}
+
+// NewResellerStats returns the ResellerStats read from the parsed responses
+// of the CMD_API_SHOW_RESELLER_USAGE and CMD_API_SHOW_RESELLER_CONFIG
+// commands. The sums of the allocations of the users are left to the caller.
+func NewResellerStats(name string, usage map[string]interface{},
+	config map[string]interface{}) ResellerStats {
+	stats := ResellerStats{
+		Name:     name,
+		Oversell: parseState(config["oversell"]),
+	}
+	stats.Usage, stats.Allocations = readResources(resellerFields, usage,
+		config)
+	return stats
+}
+
+// assignedResources returns the sums of the allocations of the users read
+// from their parsed configurations.
+func assignedResources(users []map[string]interface{}) map[string]float64 {
+	assigned := map[string]float64{}
+	for _, field := range assignedFields {
+		assigned[field] = 0
+	}
+	for _, config := range users {
+		_, allocations := readResources(assignedFields, nil, config)
+		for _, allocation := range allocations {
+			assigned[allocation.Resource] += allocation.Value
+		}
+	}
+	return assigned
+}
+
+// Oversold reports whether the allocations given to the users of
+// the reseller exceed one of its limits. Unlimited limits are never
+// oversold.
+func (r ResellerStats) Oversold() bool {
+	for _, allocation := range r.Allocations {
+		assigned, exists := r.Assigned[allocation.Resource]
+		if exists && assigned > allocation.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// resellerCollector is the subcollector exporting the usage and the limits
+// of the resellers of the target. The users of the resellers are walked in
+// the background once per interval, which is separate from the interval of
+// the updates.
+type resellerCollector struct {
+	client    *Client
+	walker    *walker
+	mutex     sync.RWMutex
+	resellers []ResellerStats
+	assigned  map[string]map[string]float64
+
+	// Metric descriptions
+	resource  *prometheus.Desc
+	unlimited *prometheus.Desc
+	oversold  *prometheus.Desc
+	oversell  *prometheus.Desc
+}
+
+// newResellerCollector returns a new resellerCollector requesting the API
+// with the client.
+func newResellerCollector(config APIConfiguration,
+	client *Client) *resellerCollector {
+	r := &resellerCollector{
+		client: client,
+		resource: prometheus.NewDesc("directadmin_reseller_resource",
+			"Usage, allocated amount or amount assigned to the users of "+
+				"the resource of the reseller, in bytes for quota and "+
+				"bandwidth, +Inf if the allocation is unlimited "+
+				"(DirectAdmin commands: CMD_API_SHOW_RESELLER_USAGE, "+
+				"CMD_API_SHOW_RESELLER_CONFIG, CMD_API_SHOW_USER_CONFIG).",
+			resellerLabels, config.Labels),
+		unlimited: prometheus.NewDesc(
+			"directadmin_reseller_allocated_unlimited",
+			"Whether the allocation of the resource of the reseller is "+
+				"unlimited (DirectAdmin command: "+
+				"CMD_API_SHOW_RESELLER_CONFIG).",
+			resellerLabels[:2], config.Labels),
+		oversold: prometheus.NewDesc("directadmin_reseller_oversold",
+			"Whether the allocations given to the users of the reseller "+
+				"exceed one of its limits.", resellerLabels[:1],
+			config.Labels),
+		oversell: prometheus.NewDesc(
+			"directadmin_reseller_oversell_allowed",
+			"Whether the reseller is allowed to oversell "+
+				"(DirectAdmin field: oversell).", resellerLabels[:1],
+			config.Labels),
+	}
+
+	interval := config.Resellers.Interval
+	if interval == 0 {
+		interval = defaultResellerInterval
+	}
+	r.walker = newWalker(interval, config.Resellers.Timeout, r.walk)
+	return r
+}
+
+// update lists the resellers and retrieves the usage and the limits of every
+// reseller, with the sums of the allocations of its users from the last walk.
+// Resellers whose requests fail keep the stats of the previous update, and
+// the last error is returned, including the one of the last walk.
+func (r *resellerCollector) update(ctx context.Context) error {
+	lastErr := r.walker.update()
+
+	// List resellers
+	list, err := r.client.Resellers(ctx)
+	if err != nil {
+		r.store(nil)
+		return err
+	}
+
+	// Get usage and limits of every reseller
+	previous := r.snapshot()
+	names := userNames(list)
+	resellers := make([]ResellerStats, 0, len(names))
+	for _, name := range names {
+		reseller, err := r.fetch(ctx, name)
+		if err != nil {
+			lastErr = err
+			var exists bool
+			if reseller, exists = previous[name]; !exists {
+				continue
+			}
+		}
+		resellers = append(resellers, reseller)
+	}
+
+	r.store(resellers)
+	return lastErr
+}
+
+// fetch retrieves the usage and the limits of the reseller.
+func (r *resellerCollector) fetch(ctx context.Context,
+	name string) (ResellerStats, error) {
+	usage, err := r.client.ResellerUsage(ctx, name)
+	if err != nil {
+		return ResellerStats{}, err
+	}
+	config, err := r.client.ResellerConfig(ctx, name)
+	if err != nil {
+		return ResellerStats{}, err
+	}
+	stats := NewResellerStats(name, usage, config)
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	stats.Assigned = r.assigned[name]
+	return stats, nil
+}
+
+// snapshot returns the resellers of the last update, indexed by name.
+func (r *resellerCollector) snapshot() map[string]ResellerStats {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	resellers := make(map[string]ResellerStats, len(r.resellers))
+	for _, reseller := range r.resellers {
+		resellers[reseller.Name] = reseller
+	}
+	return resellers
+}
+
+// walk sums the allocations of the users of every reseller. Resellers whose
+// requests fail keep the sums of the previous walk, so that a single failed
+// user doesn't drop them, and the last error is returned.
+func (r *resellerCollector) walk(ctx context.Context) error {
+	// List resellers
+	list, err := r.client.Resellers(ctx)
+	if err != nil {
+		return err
+	}
+	names := userNames(list)
+
+	// Sum allocations of the users of every reseller
+	assigned := make(map[string]map[string]float64, len(names))
+	var lastErr error
+	for _, name := range names {
+		users, err := r.fetchUsers(ctx, name)
+		if err != nil {
+			lastErr = err
+			r.mutex.RLock()
+			if sums, exists := r.assigned[name]; exists {
+				assigned[name] = sums
+			}
+			r.mutex.RUnlock()
+			continue
+		}
+		assigned[name] = assignedResources(users)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.assigned = assigned
+	return lastErr
+}
+
+// fetchUsers retrieves the configurations of the users of the reseller.
+func (r *resellerCollector) fetchUsers(ctx context.Context,
+	name string) ([]map[string]interface{}, error) {
+	list, err := r.client.ResellerUsers(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []map[string]interface{}{}
+	for _, user := range userNames(list) {
+		config, err := r.client.UserConfig(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, config)
+	}
+	return users, nil
+}
+
+// store replaces the snapshot of the collector.
+func (r *resellerCollector) store(resellers []ResellerStats) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.resellers = resellers
+}
+
+// collect sends the metrics of the resellers.
+func (r *resellerCollector) collect(ch chan<- prometheus.Metric) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, reseller := range r.resellers {
+		r.collectReseller(ch, reseller)
+	}
+}
+
+// collectReseller sends the metrics of a single reseller.
+func (r *resellerCollector) collectReseller(ch chan<- prometheus.Metric,
+	reseller ResellerStats) {
+	if reseller.Assigned != nil {
+		ch <- prometheus.MustNewConstMetric(r.oversold,
+			prometheus.GaugeValue, boolToFloat(reseller.Oversold()),
+			reseller.Name)
+	}
+	if reseller.Oversell != nil {
+		ch <- prometheus.MustNewConstMetric(r.oversell,
+			prometheus.GaugeValue, *reseller.Oversell, reseller.Name)
+	}
+
+	for _, field := range resellerFields {
+		if value, exists := reseller.Usage[field]; exists {
+			ch <- prometheus.MustNewConstMetric(r.resource,
+				prometheus.GaugeValue, value*resourceScale(field),
+				reseller.Name, field, "usage")
+		}
+	}
+	for _, field := range assignedFields {
+		if value, exists := reseller.Assigned[field]; exists {
+			ch <- prometheus.MustNewConstMetric(r.resource,
+				prometheus.GaugeValue, value*resourceScale(field),
+				reseller.Name, field, "assigned")
+		}
+	}
+	for _, allocation := range reseller.Allocations {
+		ch <- prometheus.MustNewConstMetric(r.resource,
+			prometheus.GaugeValue,
+			allocation.Value*resourceScale(allocation.Resource),
+			reseller.Name, allocation.Resource, "allocated")
+		ch <- prometheus.MustNewConstMetric(r.unlimited,
+			prometheus.GaugeValue, boolToFloat(allocation.Unlimited),
+			reseller.Name, allocation.Resource)
+	}
+}
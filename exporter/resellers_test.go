@@ -0,0 +1,290 @@
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// resellerUsers maps the resellers registered by registerResellers to
+// their users.
+var resellerUsers = map[string]string{"reseller1": "alice", "reseller2": "bob"}
+
+// resellerUsersURL returns the URL of the legacy API command listing
+// the users of the reseller.
+func resellerUsersURL(reseller string) string {
+	return "http://localhost:2222/CMD_API_SHOW_USERS?reseller=" + reseller +
+		"&json=yes"
+}
+
+// registerResellers registers the responses of the legacy API listing
+// the resellers reseller1 and reseller2, and returning the usage and
+// the configuration of each of them, and of their users alice and bob. Both
+// resellers exceed their quota, but don't oversell it.
+func registerResellers() {
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/successful.json")))
+	httpmock.RegisterResponder("GET",
+		"http://localhost:2222/CMD_API_SHOW_RESELLERS?json=yes",
+		httpmock.NewStringResponder(200, `["reseller1", "reseller2"]`))
+	for reseller, user := range resellerUsers {
+		httpmock.RegisterResponder("GET",
+			userURL("CMD_API_SHOW_RESELLER_USAGE", reseller),
+			httpmock.NewStringResponder(200,
+				responseFromFile("../testing/api/reseller-usage.json")))
+		httpmock.RegisterResponder("GET",
+			userURL("CMD_API_SHOW_RESELLER_CONFIG", reseller),
+			httpmock.NewStringResponder(200,
+				responseFromFile("../testing/api/reseller-config.json")))
+		httpmock.RegisterResponder("GET", resellerUsersURL(reseller),
+			httpmock.NewStringResponder(200, `["`+user+`"]`))
+		httpmock.RegisterResponder("GET",
+			userURL("CMD_API_SHOW_USER_CONFIG", user),
+			httpmock.NewStringResponder(200,
+				responseFromFile("../testing/api/user-config.json")))
+	}
+}
+
+// walkResellers returns a collector of the configuration, with the reseller
+// collector enabled, and its reseller collector whose users are walked once.
+// The background walks are disabled, so that the tests walk the users
+// themselves.
+func walkResellers(
+	config APIConfiguration) (*AdminStatsCollector, *resellerCollector) {
+	config.Resellers.Enabled = true
+	collector := NewAdminStatsCollector(config)
+	resellers, _ := collector.subcollectors[0].(*resellerCollector)
+	resellers.walker.start.Do(func() {})
+	resellers.walker.walkOnce()
+	return collector, resellers
+}
+
+// TestResellerStatsOversold is a unit test for the ResellerStats.Oversold
+// method.
+//
+// It builds the stats of resellers whose users have various allocations.
+// The function verifies that a reseller is oversold only when the sum of
+// the allocations of its users exceeds one of its limited allocations, and
+// that its usage doesn't matter.
+func TestResellerStatsOversold(t *testing.T) {
+	// Define tests
+	config := map[string]interface{}{
+		"quota":    "1024",
+		"vdomains": "unlimited",
+	}
+	usage := map[string]interface{}{"quota": "2048"}
+	tests := []struct {
+		name     string
+		users    []map[string]interface{}
+		expected bool
+	}{
+		{
+			name: "Within allocation",
+			users: []map[string]interface{}{
+				{"quota": "512"},
+				{"quota": "512"},
+			},
+			expected: false,
+		},
+		{
+			name: "Over allocation",
+			users: []map[string]interface{}{
+				{"quota": "512"},
+				{"quota": "512.5"},
+			},
+			expected: true,
+		},
+		{
+			name: "Unlimited user allocation",
+			users: []map[string]interface{}{
+				{"quota": "unlimited"},
+			},
+			expected: true,
+		},
+		{
+			name: "Unlimited reseller allocation",
+			users: []map[string]interface{}{
+				{"vdomains": "unlimited"},
+			},
+			expected: false,
+		},
+		{
+			name:     "No users",
+			users:    []map[string]interface{}{},
+			expected: false,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		stats := NewResellerStats("reseller1", usage, config)
+		stats.Assigned = assignedResources(test.users)
+		assert.Equal(t, test.expected, stats.Oversold(), test.name)
+	}
+}
+
+// TestResellerCollector is a unit test for the reseller collector of
+// the AdminStatsCollector.
+//
+// It registers the responses of a reseller exceeding its quota, walks its
+// users and updates the collector. The function verifies the metrics of
+// the reseller and that exceeding its quota isn't overselling.
+func TestResellerCollector(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerResellers()
+	httpmock.RegisterResponder("GET",
+		"http://localhost:2222/CMD_API_SHOW_RESELLERS?json=yes",
+		httpmock.NewStringResponder(200, `["reseller1"]`))
+
+	// Update metrics
+	collector, _ := walkResellers(config)
+	assert.Nil(t, collector.Update(context.Background()))
+	assert.Equal(t, 7, httpmock.GetTotalCallCount())
+
+	// Expected metrics
+	expected := `
+# HELP directadmin_reseller_oversell_allowed Whether the reseller is allowed to oversell (DirectAdmin field: oversell).
+# TYPE directadmin_reseller_oversell_allowed gauge
+directadmin_reseller_oversell_allowed{reseller="reseller1"} 1
+# HELP directadmin_reseller_oversold Whether the allocations given to the users of the reseller exceed one of its limits.
+# TYPE directadmin_reseller_oversold gauge
+directadmin_reseller_oversold{reseller="reseller1"} 0
+# HELP directadmin_reseller_resource Usage, allocated amount or amount assigned to the users of the resource of the reseller, in bytes for quota and bandwidth, +Inf if the allocation is unlimited (DirectAdmin commands: CMD_API_SHOW_RESELLER_USAGE, CMD_API_SHOW_RESELLER_CONFIG, CMD_API_SHOW_USER_CONFIG).
+# TYPE directadmin_reseller_resource gauge
+directadmin_reseller_resource{kind="allocated",reseller="reseller1",resource="bandwidth"} 1.073741824e+11
+directadmin_reseller_resource{kind="allocated",reseller="reseller1",resource="nusers"} 20
+directadmin_reseller_resource{kind="allocated",reseller="reseller1",resource="quota"} 5.36870912e+10
+directadmin_reseller_resource{kind="allocated",reseller="reseller1",resource="vdomains"} +Inf
+directadmin_reseller_resource{kind="assigned",reseller="reseller1",resource="bandwidth"} 1.073741824e+10
+directadmin_reseller_resource{kind="assigned",reseller="reseller1",resource="quota"} 2.147483648e+09
+directadmin_reseller_resource{kind="assigned",reseller="reseller1",resource="vdomains"} 5
+directadmin_reseller_resource{kind="usage",reseller="reseller1",resource="bandwidth"} 2.147483648e+10
+directadmin_reseller_resource{kind="usage",reseller="reseller1",resource="nusers"} 12
+directadmin_reseller_resource{kind="usage",reseller="reseller1",resource="quota"} 6.442450944e+10
+directadmin_reseller_resource{kind="usage",reseller="reseller1",resource="vdomains"} 35
+` // nolint: revive
+
+	// Test
+	err := testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_reseller_oversell_allowed",
+		"directadmin_reseller_oversold", "directadmin_reseller_resource")
+	assert.Nil(t, err)
+}
+
+// TestResellerCollectorErrors is a unit test for the failed requests of
+// the reseller collector.
+//
+// It registers failed responses of the reseller commands, walks the users of
+// the resellers and updates the collector. The function verifies that
+// the errors are counted, but don't mark the target as down, that failed
+// resellers are skipped and that resellers whose users fail are exported
+// without the sums of the allocations of their users.
+func TestResellerCollectorErrors(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		name     string
+		url      string
+		exported int
+		assigned int
+	}{
+		{
+			name: "Failed reseller list",
+			url:  "http://localhost:2222/CMD_API_SHOW_RESELLERS?json=yes",
+		},
+		{
+			name:     "Failed reseller usage",
+			url:      userURL("CMD_API_SHOW_RESELLER_USAGE", "reseller2"),
+			exported: 1,
+			assigned: 1,
+		},
+		{
+			name:     "Failed reseller config",
+			url:      userURL("CMD_API_SHOW_RESELLER_CONFIG", "reseller2"),
+			exported: 1,
+			assigned: 1,
+		},
+		{
+			name:     "Failed reseller users",
+			url:      resellerUsersURL("reseller2"),
+			exported: 2,
+			assigned: 1,
+		},
+		{
+			name:     "Failed user config",
+			url:      userURL("CMD_API_SHOW_USER_CONFIG", "bob"),
+			exported: 2,
+			assigned: 1,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		httpmock.Activate()
+		registerResellers()
+		httpmock.RegisterResponder("GET", test.url,
+			httpmock.NewStringResponder(500, ""))
+
+		collector, _ := walkResellers(config)
+		err := collector.Update(context.Background())
+		assert.ErrorIs(t, err, ErrHTTPStatus, test.name)
+
+		// Test
+		assert.Equal(t, 1.0, collector.errors[ReasonHTTP], test.name)
+		assert.Equal(t, 1.0, collector.up, test.name)
+		count, err := testutil.GatherAndCount(collector.Registry(),
+			"directadmin_reseller_oversell_allowed")
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.exported, count, test.name)
+		count, err = testutil.GatherAndCount(collector.Registry(),
+			"directadmin_reseller_oversold")
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.assigned, count, test.name)
+
+		httpmock.DeactivateAndReset()
+	}
+}
+
+// TestResellerCollectorPrevious is a unit test for the failed requests of
+// the reseller collector after a successful update.
+//
+// It walks the users of the resellers and updates the collector, then fails
+// the requests of a reseller and of a user of the other reseller and does
+// it again. The function verifies that both resellers keep their previous
+// metrics.
+func TestResellerCollectorPrevious(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerResellers()
+
+	// Update metrics
+	collector, resellers := walkResellers(config)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Fail requests
+	for _, url := range []string{
+		userURL("CMD_API_SHOW_RESELLER_USAGE", "reseller1"),
+		userURL("CMD_API_SHOW_USER_CONFIG", "bob"),
+	} {
+		httpmock.RegisterResponder("GET", url,
+			httpmock.NewStringResponder(500, ""))
+	}
+	resellers.walker.walkOnce()
+	err := collector.Update(context.Background())
+	assert.ErrorIs(t, err, ErrHTTPStatus)
+
+	// Test
+	for _, name := range []string{"directadmin_reseller_oversell_allowed",
+		"directadmin_reseller_oversold"} {
+		count, err := testutil.GatherAndCount(collector.Registry(), name)
+		assert.Nil(t, err, name)
+		assert.Equal(t, 2, count, name)
+	}
+}
@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// defaultRetryBackoff is the delay before the first retry of a request.
+const defaultRetryBackoff = 500 * time.Millisecond
+
+// defaultRetryMaxBackoff is the maximum delay between the retries of
+// a request.
+const defaultRetryMaxBackoff = 10 * time.Second
+
+// temporaryStatuses lists the response statuses of the temporary failures.
+var temporaryStatuses = []int{
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// randInt64N returns a random number in [0, n), it is replaced in tests.
+var randInt64N = rand.Int64N
+
+// RetryConfiguration represents the retry settings of the requests to
+// the DirectAdmin API. Requests are not retried by default.
+type RetryConfiguration struct {
+	Retries    int           `yaml:"retries" validate:"gte=0"`
+	Backoff    time.Duration `yaml:"backoff" validate:"gte=0"`
+	MaxBackoff time.Duration `yaml:"max_backoff" validate:"gte=0"`
+}
+
+// backoff returns the jittered delay before the retry following
+// the attempt, numbered from zero. The delay doubles with every attempt up
+// to the maximum and a random half of it is dropped.
+func (r RetryConfiguration) backoff(attempt int) time.Duration {
+	delay := r.Backoff
+	if delay == 0 {
+		delay = defaultRetryBackoff
+	}
+	maxDelay := r.MaxBackoff
+	if maxDelay == 0 {
+		maxDelay = defaultRetryMaxBackoff
+	}
+
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxDelay)
+	return delay/2 + time.Duration(randInt64N(int64(delay/2)+1))
+}
+
+// isTemporary reports whether the failed request may succeed when retried,
+// because it timed out or the server is temporarily unavailable.
+func isTemporary(err error) bool {
+	var responseError *ResponseError
+	if errors.As(err, &responseError) {
+		return slices.Contains(temporaryStatuses, responseError.StatusCode)
+	}
+	var netError net.Error
+	return errors.As(err, &netError) && netError.Timeout()
+}
+
+// sleep waits for the duration or until the context is done.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
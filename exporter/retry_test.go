@@ -0,0 +1,139 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// timeoutError is a net.Error which timed out.
+type timeoutError struct{}
+
+// Error implements the error interface.
+func (timeoutError) Error() string { return "i/o timeout" }
+
+// Timeout implements the net.Error interface.
+func (timeoutError) Timeout() bool { return true }
+
+// Temporary implements the net.Error interface.
+func (timeoutError) Temporary() bool { return true }
+
+// TestRetryConfigurationBackoff tests the backoff method of
+// the RetryConfiguration type.
+func TestRetryConfigurationBackoff(t *testing.T) {
+	// Mock the random numbers with the maximum jitter
+	randInt64N = func(int64) int64 { return 0 }
+	defer func() {
+		randInt64N = rand.Int64N
+	}()
+
+	// Define tests
+	tests := []struct {
+		retry    RetryConfiguration
+		attempt  int
+		expected time.Duration
+	}{
+		{
+			retry:    RetryConfiguration{},
+			attempt:  0,
+			expected: defaultRetryBackoff / 2,
+		},
+		{
+			retry:    RetryConfiguration{Backoff: time.Second},
+			attempt:  2,
+			expected: 2 * time.Second,
+		},
+		{
+			retry:    RetryConfiguration{Backoff: time.Second},
+			attempt:  100,
+			expected: defaultRetryMaxBackoff / 2,
+		},
+		{
+			retry: RetryConfiguration{Backoff: time.Second,
+				MaxBackoff: 3 * time.Second},
+			attempt:  3,
+			expected: 1500 * time.Millisecond,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.retry.backoff(test.attempt))
+	}
+}
+
+// TestRetryConfigurationBackoffJitter tests that the backoff is at least
+// half of the delay and at most the delay.
+func TestRetryConfigurationBackoffJitter(t *testing.T) {
+	retry := RetryConfiguration{Backoff: time.Second}
+	for i := 0; i < 100; i++ {
+		backoff := retry.backoff(1)
+		assert.GreaterOrEqual(t, backoff, time.Second)
+		assert.LessOrEqual(t, backoff, 2*time.Second)
+	}
+}
+
+// TestIsTemporary tests the isTemporary function.
+func TestIsTemporary(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name: "Bad gateway",
+			err: newResponseError(ErrHTTPStatus,
+				Response{StatusCode: http.StatusBadGateway}),
+			expected: true,
+		},
+		{
+			name: "Service unavailable",
+			err: newResponseError(ErrHTTPStatus,
+				Response{StatusCode: http.StatusServiceUnavailable}),
+			expected: true,
+		},
+		{
+			name: "Internal server error",
+			err: newResponseError(ErrHTTPStatus,
+				Response{StatusCode: http.StatusInternalServerError}),
+			expected: false,
+		},
+		{
+			name:     "Timeout",
+			err:      newScrapeError(ReasonNetwork, timeoutError{}),
+			expected: true,
+		},
+		{
+			name:     "Connection refused",
+			err:      newScrapeError(ReasonNetwork, errors.New("refused")),
+			expected: false,
+		},
+		{
+			name:     "No error",
+			err:      nil,
+			expected: false,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		assert.Equal(t, test.expected, isTemporary(test.err), test.name)
+	}
+}
+
+// TestSleep tests that the sleep function stops when the context is done.
+func TestSleep(t *testing.T) {
+	// Sleep until the end of the duration
+	assert.Nil(t, sleep(context.Background(), time.Millisecond))
+
+	// Sleep with a canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sleep(ctx, time.Hour), context.Canceled)
+}
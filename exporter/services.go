@@ -0,0 +1,125 @@
+package exporter
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// serviceLabels lists the labels of the metrics of the service collector.
+var serviceLabels = []string{"service"}
+
+// ServiceCollectorConfiguration represents the settings of the service
+// collector. It is disabled by default.
+type ServiceCollectorConfiguration struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// ServiceStats represents the state of a service. Up and Enabled are nil
+// when DirectAdmin doesn't report them.
+type ServiceStats struct {
+	Name    string
+	Up      *float64
+	Enabled *float64
+}
+
+// parseServices returns the services read from the parsed response of
+// the CMD_API_SHOW_SERVICES command, sorted by name. A service is given as
+// its state, as by the legacy API, or as an object with the status and
+// enabled fields. Unknown states, such as crashed or dead, are not running.
+func parseServices(response map[string]interface{}) []ServiceStats {
+	services := []ServiceStats{}
+	for name, value := range response {
+		if name == "error" {
+			continue
+		}
+		service := ServiceStats{Name: name}
+		if fields, ok := value.(map[string]interface{}); ok {
+			service.Up = serviceUp(fields["status"])
+			service.Enabled = parseState(fields["enabled"])
+		} else {
+			service.Up = serviceUp(value)
+		}
+		services = append(services, service)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+	return services
+}
+
+// serviceUp returns the metric value of the reported state of a service,
+// 0 if the state is unknown, or nil if DirectAdmin doesn't report it.
+func serviceUp(state interface{}) *float64 {
+	if state == nil {
+		return nil
+	}
+	if up := parseState(state); up != nil {
+		return up
+	}
+	down := 0.0
+	return &down
+}
+
+// serviceCollector is the subcollector exporting the states of the services
+// of the target.
+type serviceCollector struct {
+	client   *Client
+	mutex    sync.RWMutex
+	services []ServiceStats
+
+	// Metric descriptions
+	up      *prometheus.Desc
+	enabled *prometheus.Desc
+}
+
+// newServiceCollector returns a new serviceCollector requesting the API with
+// the client.
+func newServiceCollector(config APIConfiguration,
+	client *Client) *serviceCollector {
+	return &serviceCollector{
+		client: client,
+		up: prometheus.NewDesc("directadmin_service_up",
+			"Whether the service is running "+
+				"(DirectAdmin command: CMD_API_SHOW_SERVICES).",
+			serviceLabels, config.Labels),
+		enabled: prometheus.NewDesc("directadmin_service_enabled",
+			"Whether the service is enabled "+
+				"(DirectAdmin command: CMD_API_SHOW_SERVICES).",
+			serviceLabels, config.Labels),
+	}
+}
+
+// update retrieves the states of the services.
+func (s *serviceCollector) update(ctx context.Context) error {
+	response, err := s.client.Services(ctx)
+	services := []ServiceStats{}
+	if err == nil {
+		services = parseServices(response)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.services = services
+	return err
+}
+
+// collect sends the metrics of the services. States which DirectAdmin
+// doesn't report are not exported.
+func (s *serviceCollector) collect(ch chan<- prometheus.Metric) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, service := range s.services {
+		if service.Up != nil {
+			ch <- prometheus.MustNewConstMetric(s.up, prometheus.GaugeValue,
+				*service.Up, service.Name)
+		}
+		if service.Enabled != nil {
+			ch <- prometheus.MustNewConstMetric(s.enabled,
+				prometheus.GaugeValue, *service.Enabled, service.Name)
+		}
+	}
+}
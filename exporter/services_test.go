@@ -0,0 +1,176 @@
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// servicesURL is the URL of the legacy API command listing the services.
+const servicesURL = "http://localhost:2222/CMD_API_SHOW_SERVICES?json=yes"
+
+// TestParseServices is a unit test for the parseServices function.
+//
+// It parses the services given as states and as objects. The function
+// verifies the states of the services, that unknown states are not running
+// and that missing states are nil.
+func TestParseServices(t *testing.T) {
+	// Define states
+	up, down := 1.0, 0.0
+
+	// Parse response
+	services := parseServices(map[string]interface{}{
+		"error": "0",
+		"exim":  "OFF",
+		"httpd": "ON",
+		"named": map[string]interface{}{
+			"status":  "running",
+			"enabled": true,
+		},
+		"pure-ftpd": map[string]interface{}{
+			"status":  "inactive",
+			"enabled": "no",
+		},
+		"proftpd": map[string]interface{}{
+			"status": "crashed",
+		},
+		"spamd": "unknown",
+		"sshd":  map[string]interface{}{},
+	})
+
+	// Test
+	assert.Equal(t, []ServiceStats{
+		{Name: "exim", Up: &down},
+		{Name: "httpd", Up: &up},
+		{Name: "named", Up: &up, Enabled: &up},
+		{Name: "proftpd", Up: &down},
+		{Name: "pure-ftpd", Up: &down, Enabled: &down},
+		{Name: "spamd", Up: &down},
+		{Name: "sshd"},
+	}, services)
+}
+
+// TestServiceCollector is a unit test for the service collector of
+// the AdminStatsCollector.
+//
+// It registers the response of the services and updates the collector.
+// The function verifies the states of the services.
+func TestServiceCollector(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/successful.json")))
+	httpmock.RegisterResponder("GET", servicesURL,
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/services.json")))
+
+	// Update metrics
+	services := config
+	services.Services = ServiceCollectorConfiguration{Enabled: true}
+	collector := NewAdminStatsCollector(services)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Expected metrics
+	expected := `
+# HELP directadmin_service_up Whether the service is running (DirectAdmin command: CMD_API_SHOW_SERVICES).
+# TYPE directadmin_service_up gauge
+directadmin_service_up{service="directadmin"} 1
+directadmin_service_up{service="dovecot"} 1
+directadmin_service_up{service="exim"} 0
+directadmin_service_up{service="httpd"} 1
+directadmin_service_up{service="mysqld"} 1
+directadmin_service_up{service="named"} 1
+` // nolint: revive
+
+	// Test
+	err := testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_service_up",
+		"directadmin_service_enabled")
+	assert.Nil(t, err)
+}
+
+// TestServiceCollectorREST is a unit test for the service collector with
+// a target using the REST API.
+//
+// It registers a response with the status and the enabled fields of
+// the services and updates the collector. The function verifies that both
+// states are exported.
+func TestServiceCollectorREST(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "http://localhost:2222/api/admin-usage",
+		httpmock.NewStringResponder(200, `{"usage": {"nusers": 211}}`))
+	httpmock.RegisterResponder("GET",
+		"http://localhost:2222/api/system-services",
+		httpmock.NewStringResponder(200, `{
+			"dovecot": {"status": "active", "enabled": true},
+			"exim": {"status": "failed", "enabled": true}
+		}`))
+
+	// Update metrics
+	services := config
+	services.API = APIREST
+	services.Services = ServiceCollectorConfiguration{Enabled: true}
+	collector := NewAdminStatsCollector(services)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Expected metrics
+	expected := `
+# HELP directadmin_service_enabled Whether the service is enabled (DirectAdmin command: CMD_API_SHOW_SERVICES).
+# TYPE directadmin_service_enabled gauge
+directadmin_service_enabled{service="dovecot"} 1
+directadmin_service_enabled{service="exim"} 1
+# HELP directadmin_service_up Whether the service is running (DirectAdmin command: CMD_API_SHOW_SERVICES).
+# TYPE directadmin_service_up gauge
+directadmin_service_up{service="dovecot"} 1
+directadmin_service_up{service="exim"} 0
+` // nolint: revive
+
+	// Test
+	err := testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_service_up",
+		"directadmin_service_enabled")
+	assert.Nil(t, err)
+}
+
+// TestServiceCollectorError is a unit test for a failed request of
+// the service collector.
+//
+// It registers the response of the services, updates the collector and
+// registers a failed response. The function verifies that the error is
+// counted and that the states of the services are cleared.
+func TestServiceCollectorError(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/successful.json")))
+	httpmock.RegisterResponder("GET", servicesURL,
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/services.json")))
+
+	// Update metrics
+	services := config
+	services.Services = ServiceCollectorConfiguration{Enabled: true}
+	collector := NewAdminStatsCollector(services)
+	assert.Nil(t, collector.Update(context.Background()))
+	httpmock.RegisterResponder("GET", servicesURL,
+		httpmock.NewStringResponder(503, ""))
+	assert.ErrorIs(t, collector.Update(context.Background()), ErrHTTPStatus)
+
+	// Test
+	assert.Equal(t, 1.0, collector.errors[ReasonHTTP])
+	assert.Equal(t, 1.0, collector.up)
+	count, err := testutil.GatherAndCount(collector.Registry(),
+		"directadmin_service_up")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
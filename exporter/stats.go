@@ -0,0 +1,153 @@
+package exporter
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// resourceFields lists the usage fields of the CMD_API_ADMIN_STATS response
+// which also have an allocation. They are exported as the resource label of
+// the directadmin_resource metric.
+var resourceFields = []string{
+	"bandwidth",
+	"domainptr",
+	"ftp",
+	"inode",
+	"mysql",
+	"nemailf",
+	"nemailml",
+	"nemailr",
+	"nemails",
+	"nsubdomains",
+	"quota",
+	"vdomains",
+}
+
+// knownFields lists the other top-level fields of the CMD_API_ADMIN_STATS
+// response handled by the AdminStats schema.
+var knownFields = []string{"allocated", "usage", "loadavg", "device", "disk"}
+
+// LoadAverage represents the system load averages of the server.
+type LoadAverage struct {
+	One     float64
+	Five    float64
+	Fifteen float64
+}
+
+// AdminStats represents the response of the CMD_API_ADMIN_STATS command.
+type AdminStats struct {
+	// Usage holds the usage fields listed in usageFields, indexed by
+	// the DirectAdmin field names.
+	Usage       map[string]float64
+	Allocations []Allocation
+	LoadAverage *LoadAverage
+	Filesystems []Filesystem
+
+	// Attributes holds the string fields promoted to labels of
+	// the directadmin_server_info metric.
+	Attributes map[string]string
+
+	// Unknown holds the top-level fields the schema doesn't know.
+	Unknown map[string]interface{}
+}
+
+// NewAdminStats returns the AdminStats read from the parsed API response.
+// Usage fields are read from the usage block, top-level fields are used only
+// when the block is missing.
+func NewAdminStats(response map[string]interface{},
+	config APIConfiguration) AdminStats {
+	stats := AdminStats{
+		Usage:       map[string]float64{},
+		Allocations: ParseAllocations(response),
+		Filesystems: ParseFilesystems(response, filesystemExclude(config)),
+		Attributes:  map[string]string{},
+		Unknown:     map[string]interface{}{},
+	}
+
+	// Read usage fields
+	usage, exists := response["usage"].(map[string]interface{})
+	if !exists {
+		usage = response
+	}
+	for _, field := range usageFields {
+		if value, ok := toFloat(usage[field]); ok {
+			stats.Usage[field] = value
+		}
+	}
+
+	// Read load averages
+	if loadavg, ok := response["loadavg"].(map[string]interface{}); ok {
+		stats.LoadAverage = &LoadAverage{}
+		stats.LoadAverage.One, _ = toFloat(loadavg["one"])
+		stats.LoadAverage.Five, _ = toFloat(loadavg["five"])
+		stats.LoadAverage.Fifteen, _ = toFloat(loadavg["fifteen"])
+	}
+
+	// Read attributes
+	fields := infoFields(config)
+	for _, field := range fields {
+		stats.Attributes[field], _ = response[field].(string)
+	}
+
+	// Keep unknown fields
+	for key, value := range response {
+		if !isKnownField(key) && !slices.Contains(fields, key) {
+			stats.Unknown[key] = value
+		}
+	}
+
+	return stats
+}
+
+// isKnownField reports whether the top-level field is handled by
+// the AdminStats schema.
+func isKnownField(key string) bool {
+	return slices.Contains(usageFields, key) ||
+		slices.Contains(knownFields, key) || diskKeyRegexp.MatchString(key)
+}
+
+// toFloat converts a single value of the API response to a float64.
+func toFloat(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case string:
+		float, err := strconv.ParseFloat(value, 64)
+		return float, err == nil
+	case float64:
+		return value, true
+	case bool:
+		return boolToFloat(value), true
+	}
+	return 0, false
+}
+
+// states maps the states of the services and of the license reported by
+// DirectAdmin to metric values.
+var states = map[string]float64{
+	"on":       1,
+	"yes":      1,
+	"true":     1,
+	"running":  1,
+	"active":   1,
+	"off":      0,
+	"no":       0,
+	"false":    0,
+	"stopped":  0,
+	"inactive": 0,
+	"failed":   0,
+}
+
+// parseState returns the metric value of a state, given as a boolean or as
+// one of the states, or nil if the state is unknown.
+func parseState(state interface{}) *float64 {
+	switch state := state.(type) {
+	case bool:
+		value := boolToFloat(state)
+		return &value
+	case string:
+		if value, exists := states[strings.ToLower(state)]; exists {
+			return &value
+		}
+	}
+	return nil
+}
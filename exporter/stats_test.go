@@ -0,0 +1,70 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNewAdminStats tests the NewAdminStats function.
+func TestNewAdminStats(t *testing.T) {
+	// Define testing data
+	given := map[string]interface{}{
+		"bandwidth": "1",
+		"quota":     "1",
+		"device":    "eth0:1",
+		"disk": map[string]interface{}{
+			"info": map[string]interface{}{"ipp": "50"},
+		},
+		"disk1":   "/dev/sda1:4:2:2:50%:/",
+		"loadavg": map[string]interface{}{"one": "2.55", "five": 2.27},
+		"usage": map[string]interface{}{
+			"bandwidth": "85541",
+			"quota":     "552070",
+			"unknown":   "1",
+		},
+		"allocated": map[string]interface{}{"quota": "845790"},
+		"version":   "1.65",
+	}
+
+	// Expected result
+	expected := AdminStats{
+		Usage:       map[string]float64{"bandwidth": 85541, "quota": 552070},
+		Allocations: []Allocation{{Resource: "quota", Value: 845790}},
+		LoadAverage: &LoadAverage{One: 2.55, Five: 2.27},
+		Filesystems: []Filesystem{{Device: "/dev/sda1", Mountpoint: "/",
+			Size: 4096, Used: 2048, Avail: 2048}},
+		Attributes: map[string]string{"device": "eth0:1"},
+		Unknown:    map[string]interface{}{"version": "1.65"},
+	}
+
+	// Test
+	assert.Equal(t, expected, NewAdminStats(given, config))
+
+	// Test with the version promoted to an attribute
+	target := config
+	target.InfoFields = []string{"version", "client_ip"}
+	stats := NewAdminStats(given, target)
+	assert.Equal(t, map[string]string{"version": "1.65", "client_ip": ""},
+		stats.Attributes)
+	assert.Empty(t, stats.Unknown)
+}
+
+// TestNewAdminStatsWithoutUsageBlock tests the NewAdminStats function with
+// a response without the usage block.
+func TestNewAdminStatsWithoutUsageBlock(t *testing.T) {
+	// Define testing data
+	given := map[string]interface{}{
+		"bandwidth": "85541",
+		"nusers":    211.0,
+		"ftp":       true,
+		"vdomains":  nil,
+	}
+
+	// Test
+	stats := NewAdminStats(given, config)
+	assert.Equal(t, map[string]float64{"bandwidth": 85541, "nusers": 211,
+		"ftp": 1}, stats.Usage)
+	assert.Nil(t, stats.LoadAverage)
+	assert.Empty(t, stats.Unknown)
+}
@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// tlsVersions maps the supported minimum TLS versions to their identifiers.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfiguration represents the TLS settings of the connections to
+// the DirectAdmin API.
+type TLSConfiguration struct {
+	CAFile     string `yaml:"ca_file"`
+	CertFile   string `yaml:"cert_file" validate:"required_with=KeyFile"`
+	KeyFile    string `yaml:"key_file" validate:"required_with=CertFile"`
+	ServerName string `yaml:"server_name"`
+	MinVersion string `yaml:"min_version"`
+
+	// InsecureSkipVerify disables the verification of the certificate of
+	// the server. It is a last resort, prefer CAFile.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+}
+
+// newTLSConfig returns the tls.Config built from the TLS settings. The files
+// of the CA bundle and the client certificate are read once.
+func newTLSConfig(config TLSConfiguration) (*tls.Config, error) {
+	// Check minimum version
+	version, exists := tlsVersions[config.MinVersion]
+	if config.MinVersion != "" && !exists {
+		return nil, fmt.Errorf("unsupported minimum TLS version %q",
+			config.MinVersion)
+	}
+
+	// Load CA bundle
+	rootCAs, err := loadCAFile(config.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:            rootCAs,
+		ServerName:         config.ServerName,
+		MinVersion:         version,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	// Load client certificate
+	if config.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(config.CertFile,
+			config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// loadCAFile returns the certificate pool read from the CA bundle. It returns
+// nil, meaning the system pool, when no bundle is configured.
+func loadCAFile(filename string) (*x509.CertPool, error) {
+	if filename == "" {
+		return nil, nil
+	}
+
+	bundle, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("%s: no certificates found", filename)
+	}
+	return pool, nil
+}
@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNewTLSConfig tests the newTLSConfig function.
+func TestNewTLSConfig(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		name   string
+		config TLSConfiguration
+		valid  bool
+	}{
+		{
+			name:   "Default settings",
+			config: TLSConfiguration{},
+			valid:  true,
+		},
+		{
+			name:   "CA bundle",
+			config: TLSConfiguration{CAFile: "../testing/tls/client.pem"},
+			valid:  true,
+		},
+		{
+			name:   "Missing CA bundle",
+			config: TLSConfiguration{CAFile: "../testing/tls/missing.pem"},
+			valid:  false,
+		},
+		{
+			name:   "Invalid CA bundle",
+			config: TLSConfiguration{CAFile: "../testing/tls/invalid.pem"},
+			valid:  false,
+		},
+		{
+			name: "Client certificate",
+			config: TLSConfiguration{
+				CertFile: "../testing/tls/client.pem",
+				KeyFile:  "../testing/tls/client-key.pem",
+			},
+			valid: true,
+		},
+		{
+			name: "Invalid client key",
+			config: TLSConfiguration{
+				CertFile: "../testing/tls/client.pem",
+				KeyFile:  "../testing/tls/invalid.pem",
+			},
+			valid: false,
+		},
+		{
+			name:   "Minimum version",
+			config: TLSConfiguration{MinVersion: "1.3"},
+			valid:  true,
+		},
+		{
+			name:   "Unsupported minimum version",
+			config: TLSConfiguration{MinVersion: "1.4"},
+			valid:  false,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		tlsConfig, err := newTLSConfig(test.config)
+		if test.valid {
+			assert.Nil(t, err, test.name)
+			assert.NotNil(t, tlsConfig, test.name)
+		} else {
+			assert.Error(t, err, test.name)
+		}
+	}
+}
+
+// TestNewTLSConfigSettings tests that the newTLSConfig function applies
+// the TLS settings.
+func TestNewTLSConfigSettings(t *testing.T) {
+	// Create TLS configuration
+	tlsConfig, err := newTLSConfig(TLSConfiguration{
+		CAFile:             "../testing/tls/client.pem",
+		CertFile:           "../testing/tls/client.pem",
+		KeyFile:            "../testing/tls/client-key.pem",
+		ServerName:         "panel.hostname.com",
+		MinVersion:         "1.2",
+		InsecureSkipVerify: true,
+	})
+
+	// Test
+	assert.Nil(t, err)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.Equal(t, "panel.hostname.com", tlsConfig.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+}
@@ -0,0 +1,265 @@
+package exporter
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// userLabels lists the labels of the metrics of the per-user collector. Only
+// the resource metric has all of them, the other metrics drop the last ones.
+var userLabels = []string{"user", "reseller", "package", "resource", "kind"}
+
+// UserCollectorConfiguration represents the settings of the per-user
+// collector. It is disabled by default, as every user costs two requests.
+type UserCollectorConfiguration struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Include and Exclude are regular expressions matching the names of
+	// the exported users. Empty expressions don't filter the users.
+	Include string `yaml:"include"`
+	Exclude string `yaml:"exclude"`
+
+	// MaxUsers caps the number of exported users, zero means no cap.
+	MaxUsers int `yaml:"max_users" validate:"gte=0"`
+}
+
+// UserStats represents the usage and the limits of a user.
+type UserStats struct {
+	Name      string
+	Reseller  string
+	Package   string
+	Suspended bool
+
+	// Usage holds the usage fields listed in resourceFields, indexed by
+	// the DirectAdmin field names.
+	Usage       map[string]float64
+	Allocations []Allocation
+}
+
+// NewUserStats returns the UserStats read from the parsed responses of
+// the CMD_API_SHOW_USER_USAGE and CMD_API_SHOW_USER_CONFIG commands.
+// The suspended field is read like the states, so that both the legacy
+// yes and no and the boolean of the REST API are understood.
+func NewUserStats(name string, usage map[string]interface{},
+	config map[string]interface{}) UserStats {
+	suspended := parseState(config["suspended"])
+	stats := UserStats{
+		Name:      name,
+		Suspended: suspended != nil && *suspended == 1,
+	}
+	stats.Reseller, _ = config["creator"].(string)
+	stats.Package, _ = config["package"].(string)
+	stats.Usage, stats.Allocations = readResources(resourceFields, usage,
+		config)
+	return stats
+}
+
+// readResources returns the usage and the allocations of the resource
+// fields, read from the usage and the configuration responses of a user or
+// a reseller.
+func readResources(fields []string, usage map[string]interface{},
+	config map[string]interface{}) (map[string]float64, []Allocation) {
+	used := map[string]float64{}
+	allocated := map[string]interface{}{}
+	for _, field := range fields {
+		if value, ok := toFloat(usage[field]); ok {
+			used[field] = value
+		}
+		if value, exists := config[field]; exists {
+			allocated[field] = value
+		}
+	}
+	return used, ParseAllocations(map[string]interface{}{
+		"allocated": allocated,
+	})
+}
+
+// userNames returns the names of the users or the resellers listed in
+// the response, given as strings by the legacy API or as objects with
+// the username field by the REST API.
+func userNames(response map[string]interface{}) []string {
+	items, _ := response["list"].([]interface{})
+	names := []string{}
+	for _, item := range items {
+		switch item := item.(type) {
+		case string:
+			names = append(names, item)
+		case map[string]interface{}:
+			if name, ok := item["username"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// filterUsers returns the sorted names of the users matching the include and
+// exclude expressions, capped to the maximum number of users. It also
+// returns the number of users dropped by the cap.
+func filterUsers(names []string,
+	config UserCollectorConfiguration) ([]string, int) {
+	include := regexp.MustCompile(config.Include)
+	exclude := regexp.MustCompile(config.Exclude)
+
+	filtered := []string{}
+	for _, name := range names {
+		if include.MatchString(name) &&
+			(config.Exclude == "" || !exclude.MatchString(name)) {
+			filtered = append(filtered, name)
+		}
+	}
+	sort.Strings(filtered)
+
+	if config.MaxUsers > 0 && len(filtered) > config.MaxUsers {
+		return filtered[:config.MaxUsers], len(filtered) - config.MaxUsers
+	}
+	return filtered, 0
+}
+
+// userCollector is the subcollector exporting the usage and the limits of
+// the users of the target.
+type userCollector struct {
+	config  APIConfiguration
+	client  *Client
+	mutex   sync.RWMutex
+	users   []UserStats
+	skipped float64
+
+	// Metric descriptions
+	resource  *prometheus.Desc
+	unlimited *prometheus.Desc
+	suspended *prometheus.Desc
+}
+
+// newUserCollector returns a new userCollector requesting the API with
+// the client.
+func newUserCollector(config APIConfiguration, client *Client) *userCollector {
+	labels := userLabels[:3]
+	return &userCollector{
+		config: config,
+		client: client,
+		resource: prometheus.NewDesc("directadmin_user_resource",
+			"Usage or allocated amount of the resource of the user, in "+
+				"bytes for quota and bandwidth, +Inf if the allocation is "+
+				"unlimited (DirectAdmin commands: CMD_API_SHOW_USER_USAGE, "+
+				"CMD_API_SHOW_USER_CONFIG).",
+			userLabels, config.Labels),
+		unlimited: prometheus.NewDesc("directadmin_user_allocated_unlimited",
+			"Whether the allocation of the resource of the user is "+
+				"unlimited (DirectAdmin command: CMD_API_SHOW_USER_CONFIG).",
+			userLabels[:4], config.Labels),
+		suspended: prometheus.NewDesc("directadmin_user_suspended",
+			"Whether the user is suspended (DirectAdmin field: suspended).",
+			labels, config.Labels),
+	}
+}
+
+// update lists the users and retrieves the usage and the limits of every
+// exported user. Users whose requests fail keep the stats of the previous
+// update, so that their series don't flap, and the last error is returned.
+func (u *userCollector) update(ctx context.Context) error {
+	// List users
+	list, err := u.client.Users(ctx)
+	if err != nil {
+		u.store(nil, 0)
+		return err
+	}
+	names, skipped := filterUsers(userNames(list), u.config.Users)
+
+	// Get usage and limits of every user
+	previous := u.snapshot()
+	users := make([]UserStats, 0, len(names))
+	var lastErr error
+	for _, name := range names {
+		user, err := u.fetch(ctx, name)
+		if err != nil {
+			lastErr = err
+			var exists bool
+			if user, exists = previous[name]; !exists {
+				continue
+			}
+		}
+		users = append(users, user)
+	}
+
+	u.store(users, skipped)
+	return lastErr
+}
+
+// fetch retrieves the usage and the limits of the user.
+func (u *userCollector) fetch(ctx context.Context,
+	name string) (UserStats, error) {
+	usage, err := u.client.UserUsage(ctx, name)
+	if err != nil {
+		return UserStats{}, err
+	}
+	config, err := u.client.UserConfig(ctx, name)
+	if err != nil {
+		return UserStats{}, err
+	}
+	return NewUserStats(name, usage, config), nil
+}
+
+// snapshot returns the users of the last update, indexed by name.
+func (u *userCollector) snapshot() map[string]UserStats {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+	users := make(map[string]UserStats, len(u.users))
+	for _, user := range u.users {
+		users[user.Name] = user
+	}
+	return users
+}
+
+// store replaces the snapshot of the collector.
+func (u *userCollector) store(users []UserStats, skipped int) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.users = users
+	u.skipped = float64(skipped)
+}
+
+// collect sends the metrics of the users.
+func (u *userCollector) collect(ch chan<- prometheus.Metric) {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	for _, user := range u.users {
+		u.collectUser(ch, user)
+	}
+
+	desc := prometheus.NewDesc("directadmin_user_skipped",
+		"Number of users matching the filters, but not exported because "+
+			"of the max users cap.", nil, u.config.Labels)
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue,
+		u.skipped)
+}
+
+// collectUser sends the metrics of a single user.
+func (u *userCollector) collectUser(ch chan<- prometheus.Metric,
+	user UserStats) {
+	labels := []string{user.Name, user.Reseller, user.Package}
+	ch <- prometheus.MustNewConstMetric(u.suspended, prometheus.GaugeValue,
+		boolToFloat(user.Suspended), labels...)
+
+	for _, field := range resourceFields {
+		if value, exists := user.Usage[field]; exists {
+			ch <- prometheus.MustNewConstMetric(u.resource,
+				prometheus.GaugeValue, value*resourceScale(field),
+				append(labels, field, "usage")...)
+		}
+	}
+	for _, allocation := range user.Allocations {
+		ch <- prometheus.MustNewConstMetric(u.resource,
+			prometheus.GaugeValue,
+			allocation.Value*resourceScale(allocation.Resource),
+			append(labels, allocation.Resource, "allocated")...)
+		ch <- prometheus.MustNewConstMetric(u.unlimited,
+			prometheus.GaugeValue, boolToFloat(allocation.Unlimited),
+			append(labels, allocation.Resource)...)
+	}
+}
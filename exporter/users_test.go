@@ -0,0 +1,317 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// userURL returns the URL of the legacy API command of the user.
+func userURL(command string, user string) string {
+	return "http://localhost:2222/" + command + "?user=" + user + "&json=yes"
+}
+
+// registerUsers registers the responses of the legacy API listing the users
+// admin, alice and bob, and returning the usage and the configuration of
+// each of them.
+func registerUsers() {
+	httpmock.RegisterResponder("GET", statsURL(config),
+		httpmock.NewStringResponder(200,
+			responseFromFile("../testing/api/successful.json")))
+	httpmock.RegisterResponder("GET",
+		"http://localhost:2222/CMD_API_SHOW_ALL_USERS?json=yes",
+		httpmock.NewStringResponder(200, `["bob", "alice", "admin"]`))
+	for _, user := range []string{"admin", "alice", "bob"} {
+		httpmock.RegisterResponder("GET",
+			userURL("CMD_API_SHOW_USER_USAGE", user),
+			httpmock.NewStringResponder(200,
+				responseFromFile("../testing/api/user-usage.json")))
+		httpmock.RegisterResponder("GET",
+			userURL("CMD_API_SHOW_USER_CONFIG", user),
+			httpmock.NewStringResponder(200,
+				responseFromFile("../testing/api/user-config.json")))
+	}
+}
+
+// TestUserNames is a unit test for the userNames function.
+//
+// It parses the user lists of the legacy and of the REST API. The function
+// verifies that the names are read from both formats.
+func TestUserNames(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		name     string
+		response map[string]interface{}
+		expected []string
+	}{
+		{
+			name: "Legacy API",
+			response: map[string]interface{}{
+				"list": []interface{}{"alice", "bob"},
+			},
+			expected: []string{"alice", "bob"},
+		},
+		{
+			name: "REST API",
+			response: map[string]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"username": "alice"},
+					map[string]interface{}{"name": "bob"},
+				},
+			},
+			expected: []string{"alice"},
+		},
+		{
+			name:     "Missing list",
+			response: map[string]interface{}{},
+			expected: []string{},
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		assert.Equal(t, test.expected, userNames(test.response), test.name)
+	}
+}
+
+// TestFilterUsers is a unit test for the filterUsers function.
+//
+// It filters a list of users with various settings. The function verifies
+// that the names are filtered, sorted and capped.
+func TestFilterUsers(t *testing.T) {
+	// Define tests
+	names := []string{"carol", "admin", "bob", "alice"}
+	tests := []struct {
+		name     string
+		config   UserCollectorConfiguration
+		expected []string
+		skipped  int
+	}{
+		{
+			name:     "No filters",
+			config:   UserCollectorConfiguration{},
+			expected: []string{"admin", "alice", "bob", "carol"},
+		},
+		{
+			name:     "Include",
+			config:   UserCollectorConfiguration{Include: "^a"},
+			expected: []string{"admin", "alice"},
+		},
+		{
+			name:     "Exclude",
+			config:   UserCollectorConfiguration{Exclude: "^admin$"},
+			expected: []string{"alice", "bob", "carol"},
+		},
+		{
+			name: "Max users",
+			config: UserCollectorConfiguration{
+				Exclude:  "^admin$",
+				MaxUsers: 2,
+			},
+			expected: []string{"alice", "bob"},
+			skipped:  1,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		filtered, skipped := filterUsers(names, test.config)
+		assert.Equal(t, test.expected, filtered, test.name)
+		assert.Equal(t, test.skipped, skipped, test.name)
+	}
+}
+
+// TestNewUserStats is a unit test for the NewUserStats function.
+//
+// It reads the usage and the configuration of a user. The function verifies
+// the labels, the usage and the allocations of the user.
+func TestNewUserStats(t *testing.T) {
+	// Parse responses
+	var usage, config map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(responseFromFile(
+		"../testing/api/user-usage.json")), &usage))
+	assert.Nil(t, json.Unmarshal([]byte(responseFromFile(
+		"../testing/api/user-config.json")), &config))
+
+	// Test
+	stats := NewUserStats("alice", usage, config)
+	assert.Equal(t, "alice", stats.Name)
+	assert.Equal(t, "reseller1", stats.Reseller)
+	assert.Equal(t, "basic", stats.Package)
+	assert.False(t, stats.Suspended)
+	assert.Len(t, stats.Usage, len(resourceFields))
+	assert.Equal(t, 1024.5, stats.Usage["bandwidth"])
+	assert.Contains(t, stats.Allocations,
+		Allocation{Resource: "quota", Value: 2048})
+	assert.Contains(t, stats.Allocations,
+		Allocation{Resource: "inode", Value: math.Inf(1), Unlimited: true})
+	assert.Len(t, stats.Allocations, len(resourceFields))
+}
+
+// TestNewUserStatsSuspended is a unit test for the suspended field read by
+// the NewUserStats function.
+//
+// It reads the configurations of users with the suspended field of
+// the legacy and of the REST API. The function verifies that both formats
+// are understood, and that unknown values aren't suspended.
+func TestNewUserStatsSuspended(t *testing.T) {
+	// Define tests
+	tests := []struct {
+		name      string
+		suspended interface{}
+		expected  bool
+	}{
+		{name: "Legacy suspended", suspended: "yes", expected: true},
+		{name: "Legacy active", suspended: "no", expected: false},
+		{name: "REST suspended", suspended: true, expected: true},
+		{name: "REST active", suspended: false, expected: false},
+		{name: "Unknown", suspended: "maybe", expected: false},
+		{name: "Missing", expected: false},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		config := map[string]interface{}{}
+		if test.suspended != nil {
+			config["suspended"] = test.suspended
+		}
+		stats := NewUserStats("alice", nil, config)
+		assert.Equal(t, test.expected, stats.Suspended, test.name)
+	}
+}
+
+// TestUserCollector is a unit test for the per-user collector of
+// the AdminStatsCollector.
+//
+// It registers the responses of three users and updates a collector
+// exporting one of them. The function verifies the metrics of the user and
+// the number of users dropped by the cap.
+func TestUserCollector(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerUsers()
+
+	// Update metrics
+	users := config
+	users.Users = UserCollectorConfiguration{
+		Enabled:  true,
+		Exclude:  "^admin$",
+		MaxUsers: 1,
+	}
+	collector := NewAdminStatsCollector(users)
+	assert.Nil(t, collector.Update(context.Background()))
+	assert.Equal(t, 4, httpmock.GetTotalCallCount())
+
+	// Expected metrics
+	expected := `
+# HELP directadmin_user_skipped Number of users matching the filters, but not exported because of the max users cap.
+# TYPE directadmin_user_skipped gauge
+directadmin_user_skipped 1
+# HELP directadmin_user_suspended Whether the user is suspended (DirectAdmin field: suspended).
+# TYPE directadmin_user_suspended gauge
+directadmin_user_suspended{package="basic",reseller="reseller1",user="alice"} 0
+` // nolint: revive
+
+	// Test
+	err := testutil.GatherAndCompare(collector.Registry(),
+		strings.NewReader(expected), "directadmin_user_skipped",
+		"directadmin_user_suspended")
+	assert.Nil(t, err)
+	count, err := testutil.GatherAndCount(collector.Registry(),
+		"directadmin_user_resource", "directadmin_user_allocated_unlimited")
+	assert.Nil(t, err)
+	assert.Equal(t, 3*len(resourceFields), count)
+}
+
+// TestUserCollectorErrors is a unit test for the failed requests of
+// the per-user collector.
+//
+// It registers failed responses of the user commands and updates
+// the collector. The function verifies that the errors are counted, but
+// don't mark the target as down, and that failed users are skipped.
+func TestUserCollectorErrors(t *testing.T) {
+	// Define configuration
+	users := config
+	users.Users = UserCollectorConfiguration{Enabled: true}
+
+	// Define tests
+	tests := []struct {
+		name     string
+		url      string
+		exported int
+	}{
+		{
+			name: "Failed user list",
+			url:  "http://localhost:2222/CMD_API_SHOW_ALL_USERS?json=yes",
+		},
+		{
+			name:     "Failed user usage",
+			url:      userURL("CMD_API_SHOW_USER_USAGE", "bob"),
+			exported: 2,
+		},
+		{
+			name:     "Failed user config",
+			url:      userURL("CMD_API_SHOW_USER_CONFIG", "bob"),
+			exported: 2,
+		},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		httpmock.Activate()
+		registerUsers()
+		httpmock.RegisterResponder("GET", test.url,
+			httpmock.NewStringResponder(500, ""))
+
+		collector := NewAdminStatsCollector(users)
+		err := collector.Update(context.Background())
+		assert.ErrorIs(t, err, ErrHTTPStatus, test.name)
+
+		// Test
+		assert.Equal(t, 1.0, collector.errors[ReasonHTTP], test.name)
+		assert.Equal(t, 1.0, collector.up, test.name)
+		count, err := testutil.GatherAndCount(collector.Registry(),
+			"directadmin_user_suspended")
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.exported, count, test.name)
+
+		httpmock.DeactivateAndReset()
+	}
+}
+
+// TestUserCollectorPrevious is a unit test for the failed requests of
+// the per-user collector after a successful update.
+//
+// It updates the collector, then fails the requests of a user and updates
+// it again. The function verifies that the user keeps its previous metrics.
+func TestUserCollectorPrevious(t *testing.T) {
+	// Activate HTTP mock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerUsers()
+
+	// Update metrics
+	users := config
+	users.Users = UserCollectorConfiguration{Enabled: true}
+	collector := NewAdminStatsCollector(users)
+	assert.Nil(t, collector.Update(context.Background()))
+
+	// Fail requests
+	httpmock.RegisterResponder("GET", userURL("CMD_API_SHOW_USER_USAGE", "bob"),
+		httpmock.NewStringResponder(500, ""))
+	err := collector.Update(context.Background())
+	assert.ErrorIs(t, err, ErrHTTPStatus)
+
+	// Test
+	count, err := testutil.GatherAndCount(collector.Registry(),
+		"directadmin_user_suspended")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+}
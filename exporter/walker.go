@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultWalkTimeout is the default timeout of a background walk.
+const defaultWalkTimeout = 10 * time.Minute
+
+// walker runs a walk of the API in the background once per interval, such
+// as the walk of the domains of every user, so that it neither outlasts nor
+// uses up the deadline of the updates. Every walk has its own timeout.
+type walker struct {
+	interval time.Duration
+	timeout  time.Duration
+	walk     func(ctx context.Context) error
+	start    sync.Once
+	mutex    sync.Mutex
+	err      error
+}
+
+// newWalker returns a new walker running the walk once per interval with
+// the timeout, or with defaultWalkTimeout if the timeout is zero.
+func newWalker(interval time.Duration, timeout time.Duration,
+	walk func(ctx context.Context) error) *walker {
+	if timeout == 0 {
+		timeout = defaultWalkTimeout
+	}
+	return &walker{interval: interval, timeout: timeout, walk: walk}
+}
+
+// update starts the background walks with the first call, and returns
+// the error of the last finished walk once, so that it is counted once.
+func (w *walker) update() error {
+	w.start.Do(func() {
+		go w.run()
+	})
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// run walks right away, then once per interval.
+func (w *walker) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.walkOnce()
+		<-ticker.C
+	}
+}
+
+// walkOnce runs a single walk within its timeout and records its error.
+func (w *walker) walkOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+	err := w.walk(ctx)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.err = err
+}
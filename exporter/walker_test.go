@@ -0,0 +1,58 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWalker is a unit test for the walker.
+//
+// It starts a walker whose walks wait for their results. The function
+// verifies that the first update doesn't wait for the walk, that the error
+// of a walk is returned once and that the walk is repeated once per
+// interval.
+func TestWalker(t *testing.T) {
+	// Define walker
+	var calls atomic.Int32
+	results := make(chan error)
+	w := newWalker(time.Millisecond, 0, func(context.Context) error {
+		calls.Add(1)
+		return <-results
+	})
+	assert.Equal(t, defaultWalkTimeout, w.timeout)
+
+	// Test
+	assert.Nil(t, w.update())
+	results <- ErrHTTPStatus
+	assert.Eventually(t, func() bool {
+		return errors.Is(w.update(), ErrHTTPStatus)
+	}, time.Second, time.Millisecond)
+	assert.Nil(t, w.update())
+	results <- nil
+	assert.Eventually(t, func() bool {
+		return calls.Load() == 3
+	}, time.Second, time.Millisecond)
+}
+
+// TestWalkerTimeout is a unit test for the timeout of the walks of
+// the walker.
+//
+// It starts a walker whose walk waits for the end of its context.
+// The function verifies that the walk is canceled after the timeout.
+func TestWalkerTimeout(t *testing.T) {
+	// Define walker
+	w := newWalker(time.Hour, time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	// Test
+	assert.Eventually(t, func() bool {
+		return errors.Is(w.update(), context.DeadlineExceeded)
+	}, time.Second, time.Millisecond)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -16,10 +17,25 @@ func main() {
 	port := flag.Int("port", 8080, "Port number for the HTTP server")
 	ipAddress := flag.String("ip", "", "IP address for the HTTP server")
 	envFile := flag.String("config", "", "Configuration file path")
+	configFile := flag.String("config-file", "",
+		"YAML configuration file path with multiple targets")
 	interval := flag.Duration("interval", 10*time.Second,
 		"Interval between API requests")
 	flag.Parse()
 
+	// Run HTTP server with targets from the YAML configuration file
+	addr := fmt.Sprintf("%s:%d", *ipAddress, *port)
+	if *configFile != "" {
+		configuration, err := exporter.LoadConfiguration(*configFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		http.Handle("/metrics", promhttp.Handler())
+		http.Handle("/probe",
+			exporter.ProbeHandler(configuration.TargetsByName()))
+		log.Fatal(http.ListenAndServe(addr, nil))
+	}
+
 	// Get API configuration
 	config := exporter.NewAPIConfiguration(*envFile)
 
@@ -29,15 +45,23 @@ func main() {
 	}
 
 	// Record metrics
+	collector := exporter.NewAdminStatsCollector(config)
 	go func() {
 		for {
-			exporter.RecordMetrics(config)
+			// Errors are logged by the exporter package. A request
+			// never outlasts the interval, so a hanging API cannot
+			// block the loop.
+			ctx, cancel := context.WithTimeout(context.Background(),
+				*interval)
+			_ = collector.Update(ctx)
+			cancel()
 			time.Sleep(*interval)
 		}
 	}()
 
-	// Run HTTP server
-	addr := fmt.Sprintf("%s:%d", *ipAddress, *port)
-	http.Handle("/metrics", promhttp.Handler())
+	// Run HTTP server. The /probe endpoint is not registered, as it would
+	// request the target apart from the collector behind /metrics.
+	http.Handle("/metrics", promhttp.HandlerFor(collector.Registry(),
+		promhttp.HandlerOpts{}))
 	log.Fatal(http.ListenAndServe(addr, nil))
 }